@@ -0,0 +1,343 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+)
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// Closed lets requests through, counting failures and slow calls.
+	Closed State = iota
+	// Open fails requests fast until its duration passes.
+	Open
+	// HalfOpen lets a few probe requests through, closing the breaker if they all succeed.
+	HalfOpen
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrCircuitOpen is matched by the errors of requests failed fast by an open breaker.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitOpenError is the error of requests failed fast by an open breaker.
+type CircuitOpenError struct {
+	Breaker string    // Name of the breaker.
+	Until   time.Time // Time at which the breaker lets probe requests through.
+}
+
+// Error implements error.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker %s open until %s", e.Breaker, e.Until.Format(time.RFC3339))
+}
+
+// Is makes CircuitOpenError match ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerConfig is a struct that holds configuration options for circuit breakers.
+type BreakerConfig struct {
+	Window           time.Duration // Interval over which failures and slow calls are counted.
+	MinRequests      int           // Minimum number of requests in the window for the breaker to open.
+	FailureRatio     float64       // Ratio of failed requests, errors or 5xx responses, opening the breaker.
+	SlowCallDuration time.Duration // Duration from which requests are slow, 0 disables it.
+	SlowCallRatio    float64       // Ratio of slow requests opening the breaker.
+	OpenDuration     time.Duration // Duration requests are failed fast for before probing.
+	HalfOpenRequests int           // Number of probe requests that must succeed to close the breaker.
+	IdleTimeout      time.Duration // Duration after which unused closed breakers are removed, 0 keeps them.
+}
+
+// NewBreakerConfig creates a new BreakerConfig struct with default values.
+func NewBreakerConfig() *BreakerConfig {
+	return &BreakerConfig{
+		Window:           10 * time.Second,
+		MinRequests:      20,
+		FailureRatio:     0.5,
+		SlowCallDuration: 0,
+		SlowCallRatio:    0.8,
+		OpenDuration:     30 * time.Second,
+		HalfOpenRequests: 3,
+		IdleTimeout:      10 * time.Minute,
+	}
+}
+
+// Breaker is a circuit breaker for a dependency.
+type Breaker struct {
+	name     string
+	config   *BreakerConfig
+	onChange func(name string, from, to State)
+
+	mu          sync.Mutex
+	state       State
+	windowStart time.Time
+	requests    int
+	failures    int
+	slowCalls   int
+	openUntil   time.Time
+	probes      int // Probe requests let through while half-open.
+	successes   int // Probe requests that succeeded while half-open.
+}
+
+// Name returns the name of the breaker.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open && !time.Now().Before(b.openUntil) {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// allow returns a CircuitOpenError if the breaker doesn't let the request through.
+func (b *Breaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Closed:
+		if now.Sub(b.windowStart) >= b.config.Window {
+			b.resetWindow(now)
+		}
+		return nil
+	case Open:
+		if now.Before(b.openUntil) {
+			return &CircuitOpenError{Breaker: b.name, Until: b.openUntil}
+		}
+		b.transition(HalfOpen, now)
+	}
+
+	if b.probes >= b.config.HalfOpenRequests {
+		return &CircuitOpenError{Breaker: b.name, Until: now}
+	}
+	b.probes++
+	return nil
+}
+
+// record records the outcome of a request let through.
+func (b *Breaker) record(now time.Time, failed bool, duration time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	slow := b.config.SlowCallDuration > 0 && duration >= b.config.SlowCallDuration
+	switch b.state {
+	case Closed:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if slow {
+			b.slowCalls++
+		}
+		if b.requests >= b.config.MinRequests && b.tripped() {
+			b.transition(Open, now)
+		}
+	case HalfOpen:
+		if failed || slow {
+			b.transition(Open, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenRequests {
+			b.transition(Closed, now)
+		}
+	}
+}
+
+// release gives back the slot of a request let through whose outcome says nothing of the dependency, such as
+// one canceled by the caller.
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == HalfOpen && b.probes > b.successes {
+		b.probes--
+	}
+}
+
+// tripped checks if the failure or slow call ratio of the window reaches its threshold.
+func (b *Breaker) tripped() bool {
+	requests := float64(b.requests)
+	if float64(b.failures)/requests >= b.config.FailureRatio {
+		return true
+	}
+	return b.config.SlowCallDuration > 0 && float64(b.slowCalls)/requests >= b.config.SlowCallRatio
+}
+
+// transition changes the state of the breaker, resetting its counters.
+func (b *Breaker) transition(to State, now time.Time) {
+	from := b.state
+	b.state = to
+	b.probes, b.successes = 0, 0
+	b.resetWindow(now)
+	if to == Open {
+		b.openUntil = now.Add(b.config.OpenDuration)
+	}
+	if b.onChange != nil {
+		b.onChange(b.name, from, to)
+	}
+}
+
+// idle checks if the breaker is closed and let no request through for IdleTimeout.
+// Requests let through once a window ends start a new one, so the last request is within a window of its start.
+func (b *Breaker) idle(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state == Closed && now.Sub(b.windowStart) >= b.config.Window+b.config.IdleTimeout
+}
+
+// resetWindow starts a new counting window.
+func (b *Breaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.requests, b.failures, b.slowCalls = 0, 0, 0
+}
+
+// BreakerKeyFunc returns the name of the breaker of a request.
+type BreakerKeyFunc func(r *http.Request) string
+
+// BreakerByHost is a BreakerKeyFunc with a breaker per host.
+func BreakerByHost(r *http.Request) string {
+	return r.URL.Host
+}
+
+// BreakerByRoute returns a BreakerKeyFunc with a breaker per route, named by routes keyed by path prefix, and a
+// breaker per host for other paths.
+func BreakerByRoute(routes map[string]string) BreakerKeyFunc {
+	return func(r *http.Request) string {
+		if _, route, ok := common.MatchPrefix(r.URL.Path, routes); ok {
+			return route
+		}
+		return r.URL.Host
+	}
+}
+
+// Breakers is a set of circuit breakers sharing a configuration, created on first use.
+// It implements health.Checker, failing while a breaker is open.
+type Breakers struct {
+	config  *BreakerConfig
+	key     BreakerKeyFunc
+	metrics *Metrics
+
+	mu        sync.Mutex
+	breakers  map[string]*Breaker
+	nextSweep time.Time
+}
+
+// NewBreakers returns a set of breakers with the config, one per name returned by key.
+func NewBreakers(config *BreakerConfig, key BreakerKeyFunc) *Breakers {
+	var (
+		pkgName = reflect.TypeOf(struct{}{}).PkgPath()
+		meter   = otel.GetMeterProvider().Meter(pkgName)
+	)
+
+	return &Breakers{
+		config:   config,
+		key:      key,
+		metrics:  NewMetrics(&meter),
+		breakers: map[string]*Breaker{},
+	}
+}
+
+// Breaker returns the breaker with the name, creating it if needed.
+func (bs *Breakers) Breaker(name string) *Breaker {
+	now := time.Now()
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.config.IdleTimeout > 0 && now.After(bs.nextSweep) {
+		bs.sweep(now)
+		bs.nextSweep = now.Add(bs.config.IdleTimeout)
+	}
+
+	b, ok := bs.breakers[name]
+	if !ok {
+		b = &Breaker{name: name, config: bs.config, onChange: bs.onChange}
+		bs.breakers[name] = b
+	}
+	return b
+}
+
+// sweep removes idle breakers so memory stays bounded by the number of names in use, such as hosts.
+func (bs *Breakers) sweep(now time.Time) {
+	for name, b := range bs.breakers {
+		if b.idle(now) {
+			delete(bs.breakers, name)
+		}
+	}
+}
+
+// forRequest returns the breaker of the request.
+func (bs *Breakers) forRequest(r *http.Request) *Breaker {
+	return bs.Breaker(bs.key(r))
+}
+
+// Check implements health.Checker, returning an error naming the open breakers.
+func (bs *Breakers) Check(_ context.Context) error {
+	bs.mu.Lock()
+	breakers := make([]*Breaker, 0, len(bs.breakers))
+	for _, b := range bs.breakers {
+		breakers = append(breakers, b)
+	}
+	bs.mu.Unlock()
+
+	var open []string
+	for _, b := range breakers {
+		if b.State() == Open {
+			open = append(open, b.Name())
+		}
+	}
+	if len(open) > 0 {
+		sort.Strings(open)
+		return fmt.Errorf("%w: %s", ErrCircuitOpen, strings.Join(open, ", "))
+	}
+	return nil
+}
+
+// onChange logs and counts breaker state changes.
+func (bs *Breakers) onChange(name string, from, to State) {
+	bs.metrics.IncreaseTransitionCounter(context.Background(), name, to.String())
+
+	logger := slog.Info
+	if to == Open {
+		logger = slog.Warn
+	}
+	logger("circuit breaker state changed",
+		slog.String("breaker", name),
+		slog.String("from", from.String()),
+		slog.String("to", to.String()),
+	)
+}
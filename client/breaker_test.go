@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker(t *testing.T) {
+	config := NewBreakerConfig()
+	config.MinRequests = 4
+	config.FailureRatio = 0.5
+	config.SlowCallDuration = time.Second
+	config.SlowCallRatio = 0.75
+	config.HalfOpenRequests = 2
+
+	type call struct {
+		failed   bool
+		duration time.Duration
+	}
+
+	tests := []struct {
+		name      string
+		calls     []call
+		wantState State
+	}{
+		{"Below minimum requests", []call{{true, 0}, {true, 0}, {true, 0}}, Closed},
+		{"Failure ratio below threshold", []call{{true, 0}, {false, 0}, {false, 0}, {false, 0}}, Closed},
+		{"Failure ratio reached", []call{{true, 0}, {false, 0}, {true, 0}, {false, 0}}, Open},
+		{"Slow call ratio reached", []call{{false, 2 * time.Second}, {false, 2 * time.Second}, {false, 2 * time.Second}, {false, 0}}, Open},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreakers(config, BreakerByHost).Breaker("payments")
+			now := time.Now()
+			for _, c := range tt.calls {
+				require.NoError(t, b.allow(now))
+				b.record(now, c.failed, c.duration)
+			}
+			assert.Equal(t, tt.wantState, b.State())
+		})
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	config := NewBreakerConfig()
+	config.MinRequests = 1
+	config.OpenDuration = time.Minute
+	config.HalfOpenRequests = 2
+
+	var transitions []string
+	b := NewBreakers(config, BreakerByHost).Breaker("payments")
+	b.onChange = func(_ string, from, to State) {
+		transitions = append(transitions, from.String()+">"+to.String())
+	}
+
+	now := time.Now()
+	require.NoError(t, b.allow(now))
+	b.record(now, true, 0)
+
+	err := b.allow(now.Add(time.Second))
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.Equal(t, "payments", openErr.Breaker)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+
+	// Once open for its duration, only the probes go through.
+	later := now.Add(2 * time.Minute)
+	require.NoError(t, b.allow(later))
+	require.NoError(t, b.allow(later))
+	assert.ErrorIs(t, b.allow(later), ErrCircuitOpen)
+
+	b.record(later, false, 0)
+	b.record(later, false, 0)
+	assert.Equal(t, Closed, b.State())
+	assert.Equal(t, []string{"closed>open", "open>half_open", "half_open>closed"}, transitions)
+}
+
+func TestBreakersSweep(t *testing.T) {
+	config := NewBreakerConfig()
+	config.Window = time.Second
+	config.MinRequests = 1
+	config.IdleTimeout = time.Minute
+
+	breakers := NewBreakers(config, BreakerByHost)
+	now := time.Now()
+
+	for name, failed := range map[string]bool{"idle": false, "open": true, "active": false} {
+		b := breakers.Breaker(name)
+		require.NoError(t, b.allow(now))
+		b.record(now, failed, 0)
+	}
+	require.NoError(t, breakers.Breaker("active").allow(now.Add(61*time.Second)))
+
+	breakers.sweep(now.Add(62 * time.Second))
+
+	assert.Len(t, breakers.breakers, 2)
+	assert.Contains(t, breakers.breakers, "open", "open breakers are kept")
+	assert.Contains(t, breakers.breakers, "active")
+}
+
+func TestTransportBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	breakerConfig := NewBreakerConfig()
+	breakerConfig.MinRequests = 2
+	breakers := NewBreakers(breakerConfig, BreakerByHost)
+
+	config := NewConfig()
+	config.Breakers = breakers
+	client := New(config)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+	assert.ErrorIs(t, breakers.Check(context.Background()), ErrCircuitOpen)
+
+	_, err := client.Get(server.URL)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
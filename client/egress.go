@@ -0,0 +1,241 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/2n3g5c9/go-http/middlewares/ipfilter"
+)
+
+// DefaultBlockedNetworks are the IP ranges an EgressPolicy refuses to connect to by default: unspecified,
+// private, shared, loopback, link-local (including the cloud metadata servers), benchmarking, documentation,
+// discard, multicast and reserved ranges. IPv4-mapped IPv6 addresses are matched as IPv4, and the IPv6 ranges
+// embedding IPv4 addresses, such as NAT64 and 6to4, are blocked entirely as they can reach any of them.
+var DefaultBlockedNetworks = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.88.99.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/96",
+	"::1/128",
+	"64:ff9b::/96",
+	"64:ff9b:1::/48",
+	"100::/64",
+	"2001:db8::/32",
+	"2002::/16",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+}
+
+// ErrEgressDenied is matched by the errors of requests refused by an EgressPolicy.
+var ErrEgressDenied = errors.New("egress denied")
+
+// ErrResponseTooLarge is returned when reading a response body over the MaxResponseSize of an EgressPolicy.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// EgressError is the error of requests refused by an EgressPolicy.
+type EgressError struct {
+	Host   string // Host the request was sent to.
+	Reason string // Reason it was refused.
+}
+
+// Error implements error.
+func (e *EgressError) Error() string {
+	return fmt.Sprintf("egress to %s denied: %s", e.Host, e.Reason)
+}
+
+// Is makes EgressError match ErrEgressDenied.
+func (e *EgressError) Is(target error) bool {
+	return target == ErrEgressDenied
+}
+
+// EgressPolicy restricts the destinations of a Transport sending requests to URLs supplied by users, against
+// server-side request forgery. Host names are resolved by the policy and connections refused to blocked
+// addresses, so that DNS can't point an allowed name at an internal service. Every redirect hop is checked.
+type EgressPolicy struct {
+	AllowedHosts    []string         // Hosts requests may be sent to, "*.example.com" matching subdomains, empty allows any.
+	DeniedHosts     []string         // Hosts requests may never be sent to, with the same syntax.
+	BlockedNetworks ipfilter.Matcher // IP addresses connections are refused to, after resolution.
+	MaxResponseSize int64            // Maximum size of response bodies in bytes, 0 means unlimited.
+	MaxRedirects    int              // Maximum number of redirects followed by clients returned by New.
+	Resolver        *net.Resolver    // Resolver of host names, nil means net.DefaultResolver.
+}
+
+// NewEgressPolicy creates a new EgressPolicy struct with default values.
+func NewEgressPolicy() *EgressPolicy {
+	blocked, err := ipfilter.NewSet(DefaultBlockedNetworks)
+	if err != nil {
+		panic(err)
+	}
+
+	return &EgressPolicy{
+		AllowedHosts:    []string{},
+		DeniedHosts:     []string{},
+		BlockedNetworks: blocked,
+		MaxResponseSize: 10 << 20,
+		MaxRedirects:    5,
+		Resolver:        nil,
+	}
+}
+
+// checkURL returns an EgressError if requests may not be sent to the URL.
+func (p *EgressPolicy) checkURL(u *url.URL) error {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return &EgressError{Host: host, Reason: "scheme " + u.Scheme + " not allowed"}
+	case matchHost(host, p.DeniedHosts):
+		return &EgressError{Host: host, Reason: "host denied"}
+	case len(p.AllowedHosts) > 0 && !matchHost(host, p.AllowedHosts):
+		return &EgressError{Host: host, Reason: "host not allowed"}
+	}
+	return nil
+}
+
+// matchHost checks if the host is one of the patterns, "*.example.com" matching subdomains of example.com.
+func matchHost(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok && strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// transport returns a copy of the base transport dialing through the policy, without proxy.
+// It panics if the base transport isn't an *http.Transport, whose dialer can't be replaced otherwise.
+func (p *EgressPolicy) transport(base http.RoundTripper) *http.Transport {
+	var t *http.Transport
+	switch b := base.(type) {
+	case nil:
+		t = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		t = b.Clone()
+	default:
+		panic(fmt.Errorf("egress policy requires an *http.Transport base, got %T", base))
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	t.Proxy = nil
+	t.DialTLSContext = nil
+	t.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return p.dial(ctx, dialer, network, address)
+	}
+	return t
+}
+
+// dial resolves the host of the address and connects to its first reachable IP address, refusing to connect if
+// any of them is blocked.
+func (p *EgressPolicy) dial(ctx context.Context, dialer *net.Dialer, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := p.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if p.BlockedNetworks != nil && p.BlockedNetworks.Contains(addr) {
+			return nil, &EgressError{Host: host, Reason: "address " + addr.String() + " blocked"}
+		}
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// resolve returns the IP addresses of the host.
+func (p *EgressPolicy) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+// limitResponse fails responses declaring a body over the maximum size and limits the others.
+func (p *EgressPolicy) limitResponse(resp *http.Response) (*http.Response, error) {
+	if p.MaxResponseSize <= 0 {
+		return resp, nil
+	}
+	if resp.ContentLength > p.MaxResponseSize {
+		discard(resp)
+		return nil, fmt.Errorf("%w: %d bytes declared", ErrResponseTooLarge, resp.ContentLength)
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: p.MaxResponseSize}
+	return resp, nil
+}
+
+// checkRedirect limits the number of redirects followed.
+func (p *EgressPolicy) checkRedirect(_ *http.Request, via []*http.Request) error {
+	if len(via) > p.MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", p.MaxRedirects)
+	}
+	return nil
+}
+
+// limitedBody is a response body returning ErrResponseTooLarge once more than its maximum size is read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+// Read implements io.Reader.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = -1
+		return n, ErrResponseTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
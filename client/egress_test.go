@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2n3g5c9/go-http/middlewares/ipfilter"
+)
+
+func TestEgressPolicyCheckURL(t *testing.T) {
+	policy := NewEgressPolicy()
+	policy.AllowedHosts = []string{"hooks.example.com", "*.cdn.example.com"}
+	policy.DeniedHosts = []string{"internal.cdn.example.com"}
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{"Allowed host", "https://hooks.example.com/a", false},
+		{"Allowed host with trailing dot", "https://HOOKS.example.com./a", false},
+		{"Allowed subdomain", "https://img.cdn.example.com/a.png", false},
+		{"Denied subdomain", "https://internal.cdn.example.com/a.png", true},
+		{"Domain suffix without dot", "https://evilcdn.example.com/a.png", true},
+		{"Wildcard domain itself", "https://cdn.example.com/a.png", true},
+		{"Host not allowed", "https://example.org/a", true},
+		{"Scheme not allowed", "file:///etc/passwd", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+
+			err = policy.checkURL(u)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrEgressDenied)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEgressPolicyDial(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+	}{
+		{"Loopback", "127.0.0.1:80"},
+		{"Metadata server", "169.254.169.254:80"},
+		{"Private network", "10.1.2.3:443"},
+		{"IPv6 loopback", "[::1]:80"},
+		{"IPv4-mapped loopback", "[::ffff:127.0.0.1]:80"},
+		{"Unique local IPv6", "[fd00:ec2::254]:80"},
+		{"NAT64 metadata server", "[64:ff9b::a9fe:a9fe]:80"},
+		{"Local-use NAT64", "[64:ff9b:1::a9fe:a9fe]:80"},
+		{"6to4 metadata server", "[2002:a9fe:a9fe::1]:80"},
+		{"IPv4-compatible loopback", "[::7f00:1]:80"},
+		{"6to4 relay anycast", "192.88.99.1:80"},
+		{"Resolved name", "localhost:80"},
+	}
+
+	policy := NewEgressPolicy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := policy.dial(context.Background(), &net.Dialer{}, "tcp", tt.address)
+			assert.ErrorIs(t, err, ErrEgressDenied)
+		})
+	}
+}
+
+func TestTransportEgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			// Same server, under a host name that isn't allowed.
+			http.Redirect(w, r, strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)+"/", http.StatusFound)
+		case "/large":
+			w.Header().Set("Content-Length", "100")
+			_, _ = w.Write(make([]byte, 100))
+		case "/stream":
+			w.(http.Flusher).Flush()
+			_, _ = w.Write(make([]byte, 100))
+		default:
+			_, _ = io.WriteString(w, "ok")
+		}
+	}))
+	defer server.Close()
+
+	unblocked, err := ipfilter.NewSet(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		policy  func(*EgressPolicy)
+		path    string
+		wantErr error
+	}{
+		{"Blocked address", func(p *EgressPolicy) {}, "/", ErrEgressDenied},
+		{"Allowed address", func(p *EgressPolicy) { p.BlockedNetworks = unblocked }, "/", nil},
+		{"Redirect to denied host", func(p *EgressPolicy) {
+			p.BlockedNetworks = unblocked
+			p.AllowedHosts = []string{"127.0.0.1"}
+		}, "/redirect", ErrEgressDenied},
+		{"Declared response too large", func(p *EgressPolicy) {
+			p.BlockedNetworks = unblocked
+			p.MaxResponseSize = 10
+		}, "/large", ErrResponseTooLarge},
+		{"Streamed response too large", func(p *EgressPolicy) {
+			p.BlockedNetworks = unblocked
+			p.MaxResponseSize = 10
+		}, "/stream", ErrResponseTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.Egress = NewEgressPolicy()
+			tt.policy(config.Egress)
+
+			resp, err := New(config).Get(server.URL + tt.path)
+			if err == nil {
+				_, err = io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+			}
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
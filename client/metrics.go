@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+)
+
+// Attribute keys of the circuit breaker metrics.
+const (
+	breakerKey = attribute.Key("circuit.breaker")
+	stateKey   = attribute.Key("circuit.state")
+)
+
+type Metrics struct {
+	requestCounter    metric.Int64Counter
+	requestDuration   metric.Int64Histogram
+	transitionCounter metric.Int64Counter
+}
+
+// NewMetrics returns a new Metrics instance.
+func NewMetrics(meter *metric.Meter) *Metrics {
+	requestCounter, _ := (*meter).Int64Counter(
+		"http_client_requests_total",
+		metric.WithDescription("Total number of outbound HTTP requests."),
+	)
+
+	requestDuration, _ := (*meter).Int64Histogram(
+		"http_client_request_duration_ms",
+		metric.WithDescription("Outbound HTTP request duration in milliseconds."),
+	)
+
+	transitionCounter, _ := (*meter).Int64Counter(
+		"http_client_circuit_transitions_total",
+		metric.WithDescription("Total number of circuit breaker state changes."),
+	)
+
+	return &Metrics{
+		requestCounter:    requestCounter,
+		requestDuration:   requestDuration,
+		transitionCounter: transitionCounter,
+	}
+}
+
+// IncreaseRequestCounter increases the request counter by 1, a zero status code meaning no response.
+func (m *Metrics) IncreaseRequestCounter(ctx context.Context, method, peer string, statusCode int) {
+	m.requestCounter.Add(ctx, 1, metric.WithAttributes(attributes(method, peer, statusCode)...))
+}
+
+// RecordRequestDuration records the request duration in milliseconds, a zero status code meaning no response.
+func (m *Metrics) RecordRequestDuration(ctx context.Context, method, peer string, statusCode int, duration time.Duration) {
+	m.requestDuration.Record(ctx, duration.Milliseconds(), metric.WithAttributes(attributes(method, peer, statusCode)...))
+}
+
+// IncreaseTransitionCounter increases the circuit breaker state change counter by 1.
+func (m *Metrics) IncreaseTransitionCounter(ctx context.Context, breaker, state string) {
+	m.transitionCounter.Add(ctx, 1, metric.WithAttributes(breakerKey.String(breaker), stateKey.String(state)))
+}
+
+// attributes returns the metric attributes of a request.
+func attributes(method, peer string, statusCode int) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.HTTPMethodKey.String(method), semconv.NetPeerNameKey.String(peer)}
+	if statusCode != 0 {
+		attrs = append(attrs, semconv.HTTPStatusCodeKey.Int(statusCode))
+	}
+	return attrs
+}
@@ -0,0 +1,426 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slices"
+)
+
+// attemptKey is the attribute key for the attempt number of attempt span events.
+const attemptKey = attribute.Key("http.attempt")
+
+// idempotencyKeyHeader is the header making requests with unsafe methods retryable, as set by the idempotency
+// middleware of the called service.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxDrain is the maximum number of bytes read from discarded responses so their connection can be reused.
+const maxDrain = 4 << 10
+
+// RetryPolicy configures the retries and hedging of a Transport. Only requests with an idempotent method, or an
+// Idempotency-Key header, and a body that can be rewound with Request.GetBody are retried.
+type RetryPolicy struct {
+	MaxAttempts   int           // Maximum number of attempts, including the first one.
+	BaseDelay     time.Duration // Upper bound of the delay before the first retry, doubling with each retry.
+	MaxDelay      time.Duration // Upper bound of the delay before any retry.
+	MaxRetryAfter time.Duration // Longest Retry-After waited for, responses asking for more are returned.
+	RetryStatuses []int         // Response status codes that are retried, on top of transport errors.
+	Budget        *Budget       // Budget limiting the retries of all requests, nil means unlimited.
+	HedgeDelay    time.Duration // Delay after which GET requests without response are sent again, 0 disables hedging.
+}
+
+// NewRetryPolicy creates a new RetryPolicy struct with default values.
+func NewRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:   3,
+		BaseDelay:     100 * time.Millisecond,
+		MaxDelay:      5 * time.Second,
+		MaxRetryAfter: 30 * time.Second,
+		RetryStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		Budget:     NewBudget(0.2, 10),
+		HedgeDelay: 0,
+	}
+}
+
+// Budget limits retries to a ratio of requests, plus a minimum rate, so that retries can't amplify an outage.
+// It is safe for concurrent use and meant to be shared by the transports calling the same service.
+type Budget struct {
+	mu           sync.Mutex
+	ratio        float64
+	minPerSecond float64
+	capacity     float64
+	tokens       float64
+	last         time.Time
+}
+
+// NewBudget returns a Budget allowing a ratio of retries per request, such as 0.2 for one retry every five
+// requests, and minPerSecond retries per second regardless of the traffic.
+func NewBudget(ratio float64, minPerSecond int) *Budget {
+	capacity := 10 * float64(minPerSecond)
+	if capacity < 10 {
+		capacity = 10
+	}
+	return &Budget{
+		ratio:        ratio,
+		minPerSecond: float64(minPerSecond),
+		capacity:     capacity,
+		tokens:       capacity,
+	}
+}
+
+// deposit credits the budget for a request.
+func (b *Budget) deposit(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	b.tokens += b.ratio
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
+
+// withdraw reports whether the budget allows a retry, debiting it if so.
+func (b *Budget) withdraw(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill credits the minimum rate of retries since the last call.
+func (b *Budget) refill(now time.Time) {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.minPerSecond
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+	}
+	b.last = now
+}
+
+// retryable checks if the request can safely be sent more than once.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		if req.Header.Get(idempotencyKeyHeader) == "" {
+			return false
+		}
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns a copy of the request with the context and a fresh body.
+func rewind(ctx context.Context, req *http.Request) (*http.Request, error) {
+	attemptReq := req.Clone(ctx)
+	if req.Body == nil || req.Body == http.NoBody {
+		return attemptReq, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	attemptReq.Body = body
+	return attemptReq, nil
+}
+
+// retryDelay returns the delay before retrying an attempt, or false if it must not be retried.
+func (p *RetryPolicy) retryDelay(resp *http.Response, err error, attempt int, now time.Time) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if errors.Is(err, ErrEgressDenied) {
+		return 0, false
+	}
+	if err != nil {
+		return p.backoff(attempt), true
+	}
+	if !p.retryStatus(resp.StatusCode) {
+		return 0, false
+	}
+
+	delay := p.backoff(attempt)
+	if after, ok := retryAfter(resp.Header.Get("Retry-After"), now); ok {
+		if after > p.MaxRetryAfter {
+			return 0, false
+		}
+		if after > delay {
+			delay = after
+		}
+	}
+	return delay, true
+}
+
+// retryStatus checks if responses with the status code are retried.
+func (p *RetryPolicy) retryStatus(statusCode int) bool {
+	return slices.Contains(p.RetryStatuses, statusCode)
+}
+
+// backoff returns a random delay up to the exponential backoff of the attempt ("full jitter").
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.MaxDelay
+	if shift := attempt - 1; shift < 32 && p.BaseDelay<<shift < ceiling {
+		ceiling = p.BaseDelay << shift
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// retryAfter parses a Retry-After header value, in seconds or as an HTTP date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// send sends the request, retrying or hedging it according to the retry policy.
+func (t *Transport) send(req *http.Request, span trace.Span) (*http.Response, error) {
+	policy := t.config.Retry
+	if policy == nil || policy.MaxAttempts <= 1 || !retryable(req) {
+		return t.attempt(req, span, 1)
+	}
+
+	if policy.Budget != nil {
+		policy.Budget.deposit(time.Now())
+	}
+	if policy.HedgeDelay > 0 && req.Method == http.MethodGet {
+		return t.hedge(req, span, policy)
+	}
+	return t.retry(req, span, policy)
+}
+
+// attempt sends the request once, recording the attempt as a span event.
+func (t *Transport) attempt(req *http.Request, span trace.Span, n int) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+
+	attrs := []attribute.KeyValue{attemptKey.Int(n)}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error", err.Error()))
+	} else {
+		attrs = append(attrs, semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+	}
+	span.AddEvent("attempt", trace.WithAttributes(attrs...))
+
+	return resp, err
+}
+
+// retry sends the request until it succeeds, isn't retryable anymore or the budget is exhausted.
+func (t *Transport) retry(req *http.Request, span trace.Span, policy *RetryPolicy) (*http.Response, error) {
+	ctx := req.Context()
+	attemptReq := req
+	for n := 1; ; n++ {
+		resp, err := t.attempt(attemptReq, span, n)
+
+		delay, ok := policy.retryDelay(resp, err, n, time.Now())
+		if !ok || ctx.Err() != nil {
+			return resp, err
+		}
+		if policy.Budget != nil && !policy.Budget.withdraw(time.Now()) {
+			span.AddEvent("retry budget exhausted")
+			return resp, err
+		}
+		discard(resp)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if attemptReq, err = rewind(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// hedgeResult is the outcome of a hedged attempt.
+type hedgeResult struct {
+	n      int
+	resp   *http.Response
+	err    error
+	cancel context.CancelFunc
+}
+
+// hedge sends the request again each time the hedge delay passes without a response, up to the maximum number
+// of attempts, and returns the first successful response, canceling the other attempts. When every attempt sent
+// failed, the next one waits for the retry delay, as with retry.
+func (t *Transport) hedge(req *http.Request, span trace.Span, policy *RetryPolicy) (*http.Response, error) {
+	ctx := req.Context()
+	results := make(chan hedgeResult, policy.MaxAttempts)
+	cancels := make([]context.CancelFunc, 0, policy.MaxAttempts)
+	launch := func() bool {
+		if len(cancels) >= policy.MaxAttempts {
+			return false
+		}
+		if len(cancels) > 0 && policy.Budget != nil && !policy.Budget.withdraw(time.Now()) {
+			span.AddEvent("retry budget exhausted")
+			return false
+		}
+		attemptCtx, cancel := context.WithCancel(ctx)
+		attemptReq, err := rewind(attemptCtx, req)
+		if err != nil {
+			cancel()
+			return false
+		}
+		cancels = append(cancels, cancel)
+		n := len(cancels)
+		go func() {
+			resp, err := t.attempt(attemptReq, span, n)
+			results <- hedgeResult{n: n, resp: resp, err: err, cancel: cancel}
+		}()
+		return true
+	}
+
+	if !launch() {
+		return t.attempt(req, span, 1)
+	}
+	pending := 1
+
+	// finish cancels the other attempts, discards their outcome in the background and returns the result.
+	finish := func(res hedgeResult) (*http.Response, error) {
+		for i, cancel := range cancels {
+			if i != res.n-1 {
+				cancel()
+			}
+		}
+		go func(pending int) {
+			for i := 0; i < pending; i++ {
+				discard((<-results).resp)
+			}
+		}(pending)
+		return withCancel(res)
+	}
+
+	timer := time.NewTimer(policy.HedgeDelay)
+	defer timer.Stop()
+
+	var (
+		last    *hedgeResult
+		stopped bool // Set once a failure isn't retryable, so that no more attempts are sent.
+	)
+	for {
+		// Only wait for the context while backing off, attempts in flight report its cancellation.
+		var done <-chan struct{}
+		if pending == 0 {
+			done = ctx.Done()
+		}
+
+		select {
+		case <-done:
+			discard(last.resp)
+			return finish(hedgeResult{n: last.n, err: ctx.Err(), cancel: last.cancel})
+		case <-timer.C:
+			if !stopped && launch() {
+				pending++
+				timer.Reset(policy.HedgeDelay)
+			} else if pending == 0 {
+				return finish(*last)
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil && !policy.retryStatus(res.resp.StatusCode) {
+				if last != nil {
+					discard(last.resp)
+					last.cancel()
+				}
+				return finish(res)
+			}
+
+			// Keep the latest failure, returned if every attempt fails.
+			if last != nil {
+				discard(last.resp)
+				last.cancel()
+			}
+			last = &res
+
+			delay, ok := policy.retryDelay(res.resp, res.err, len(cancels), time.Now())
+			if !ok || ctx.Err() != nil {
+				stopped = true
+			}
+			if pending > 0 {
+				continue
+			}
+			if stopped {
+				return finish(res)
+			}
+			resetTimer(timer, delay)
+		}
+	}
+}
+
+// resetTimer stops the timer, draining its channel as required before Go 1.23, and resets it to the duration.
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
+// withCancel returns the outcome of a hedged attempt, its context being canceled once the body is closed.
+func withCancel(res hedgeResult) (*http.Response, error) {
+	if res.err != nil {
+		res.cancel()
+		return nil, res.err
+	}
+	res.resp.Body = &cancelBody{ReadCloser: res.resp.Body, cancel: res.cancel}
+	return res.resp, nil
+}
+
+// cancelBody is a response body canceling the context of its request when closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements io.Closer.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// discard drains and closes the body of a response that won't be returned, so its connection can be reused.
+func discard(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrain))
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,242 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		idempotencyKey string
+		body           io.Reader
+		want           bool
+	}{
+		{"GET", http.MethodGet, "", nil, true},
+		{"PUT with rewindable body", http.MethodPut, "", strings.NewReader("{}"), true},
+		{"PUT with one-shot body", http.MethodPut, "", io.NopCloser(strings.NewReader("{}")), false},
+		{"POST", http.MethodPost, "", strings.NewReader("{}"), false},
+		{"POST with Idempotency-Key", http.MethodPost, "k1", strings.NewReader("{}"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "http://example.com", tt.body)
+			require.NoError(t, err)
+			if tt.idempotencyKey != "" {
+				req.Header.Set("Idempotency-Key", tt.idempotencyKey)
+			}
+			assert.Equal(t, tt.want, retryable(req))
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"Seconds", "3", 3 * time.Second, true},
+		{"HTTP date", "Thu, 01 Jun 2023 12:00:10 GMT", 10 * time.Second, true},
+		{"Past HTTP date", "Thu, 01 Jun 2023 11:00:00 GMT", 0, true},
+		{"Missing", "", 0, false},
+		{"Invalid", "soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := retryAfter(tt.value, now)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	now := time.Now()
+	budget := NewBudget(0.5, 1)
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, budget.withdraw(now), "initial capacity")
+	}
+	assert.False(t, budget.withdraw(now), "exhausted")
+
+	budget.deposit(now)
+	budget.deposit(now)
+	assert.True(t, budget.withdraw(now), "deposits of two requests")
+	assert.False(t, budget.withdraw(now))
+
+	assert.True(t, budget.withdraw(now.Add(time.Second)), "minimum rate")
+}
+
+func TestTransportRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		retryAfter   string
+		failures     int32
+		wantAttempts int32
+		wantStatus   int
+	}{
+		{"Retried until success", http.MethodPut, "", 2, 3, http.StatusOK},
+		{"Attempts exhausted", http.MethodGet, "", 5, 3, http.StatusServiceUnavailable},
+		{"Non-idempotent method", http.MethodPost, "", 2, 1, http.StatusServiceUnavailable},
+		{"Retry-After honored", http.MethodGet, "0", 1, 2, http.StatusOK},
+		{"Retry-After too long", http.MethodGet, "3600", 1, 1, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				assert.Equal(t, "payload", string(body), "the body is rewound")
+				if attempts.Add(1) <= tt.failures {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+			config := NewConfig()
+			config.Retry = NewRetryPolicy()
+			config.Retry.BaseDelay = time.Millisecond
+
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader("payload"))
+			require.NoError(t, err)
+			resp, err := New(config).Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantAttempts, attempts.Load())
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			events := spans[0].Events()
+			require.Len(t, events, int(tt.wantAttempts))
+			for i, event := range events {
+				assert.Contains(t, event.Attributes, attemptKey.Int(i+1))
+			}
+		})
+	}
+}
+
+func TestTransportHedge(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			// The first attempt is slow, until it is canceled.
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = io.WriteString(w, "hedged")
+	}))
+	defer server.Close()
+
+	config := NewConfig()
+	config.Retry = NewRetryPolicy()
+	config.Retry.HedgeDelay = 10 * time.Millisecond
+
+	start := time.Now()
+	resp, err := New(config).Get(server.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, "hedged", string(body))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestTransportHedgeFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		retryAfter   string
+		egress       bool
+		wantAttempts int
+		wantStatus   int
+		wantMinDelay time.Duration
+	}{
+		{"Retried after backoff", "", false, 2, http.StatusOK, 0},
+		{"Retry-After honored", "1", false, 2, http.StatusOK, time.Second},
+		{"Retry-After too long", "3600", false, 1, http.StatusServiceUnavailable, 0},
+		{"Egress denied", "", true, 1, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) == 1 {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+			config := NewConfig()
+			config.Retry = NewRetryPolicy()
+			config.Retry.BaseDelay = time.Millisecond
+			// Only failures send the request again.
+			config.Retry.HedgeDelay = time.Hour
+			if tt.egress {
+				config.Egress = NewEgressPolicy()
+			}
+
+			start := time.Now()
+			resp, err := New(config).Get(server.URL)
+			if tt.egress {
+				assert.ErrorIs(t, err, ErrEgressDenied)
+			} else {
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			}
+			assert.GreaterOrEqual(t, time.Since(start), tt.wantMinDelay)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			var sent int
+			for _, event := range spans[0].Events() {
+				if event.Name == "attempt" {
+					sent++
+				}
+			}
+			assert.Equal(t, tt.wantAttempts, sent)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"time"
+
+	gcppropagator "github.com/GoogleCloudPlatform/opentelemetry-operations-go/propagator"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/logging"
+	"github.com/2n3g5c9/go-http/middlewares/requestid"
+)
+
+// Config is a struct that holds configuration options for the instrumented transport.
+type Config struct {
+	Base                http.RoundTripper             // Transport sending the requests, nil means http.DefaultTransport.
+	Retry               *RetryPolicy                  // Policy retrying failed requests, nil disables retries.
+	Breakers            *Breakers                     // Circuit breakers failing requests fast, nil disables them.
+	Egress              *EgressPolicy                 // Policy restricting destinations, nil allows any.
+	Propagator          propagation.TextMapPropagator // Propagator injecting the trace context into request headers.
+	RequestIDHeader     string                        // Header carrying the request ID, empty disables it.
+	RedactedQueryParams []string                      // Query parameters redacted from traced URLs, on top of common.SensitiveQueryParams.
+}
+
+// NewConfig creates a new Config struct with default values.
+// The default propagator injects both the W3C traceparent and the Google Cloud X-Cloud-Trace-Context headers.
+func NewConfig() *Config {
+	return &Config{
+		Base:     nil,
+		Retry:    nil,
+		Breakers: nil,
+		Egress:   nil,
+		Propagator: propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+			gcppropagator.CloudTraceFormatPropagator{},
+		),
+		RequestIDHeader:     requestid.Header,
+		RedactedQueryParams: []string{},
+	}
+}
+
+// Transport is a http.RoundTripper tracing outbound requests as client spans, propagating the trace context and
+// request ID, recording metrics and logging failures. With a retry policy, attempts are span events, and with
+// circuit breakers, requests to failing dependencies fail fast with a CircuitOpenError. With an egress policy,
+// requests to refused destinations fail with an EgressError.
+type Transport struct {
+	config  *Config
+	base    http.RoundTripper
+	tracer  trace.Tracer
+	metrics *Metrics
+}
+
+// NewTransport returns a Transport with the Config.
+func NewTransport(config *Config) *Transport {
+	var (
+		pkgName = reflect.TypeOf(struct{}{}).PkgPath()
+		meter   = otel.GetMeterProvider().Meter(pkgName)
+	)
+
+	base := config.Base
+	if config.Egress != nil {
+		base = config.Egress.transport(base)
+	} else if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Transport{
+		config:  config,
+		base:    base,
+		tracer:  otel.Tracer(pkgName),
+		metrics: NewMetrics(&meter),
+	}
+}
+
+// New returns a http.Client with a Transport with the Config, limiting redirects with an egress policy.
+func New(config *Config) *http.Client {
+	client := &http.Client{Transport: NewTransport(config)}
+	if config.Egress != nil {
+		client.CheckRedirect = config.Egress.checkRedirect
+	}
+	return client
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := common.RedactedURL(req.URL, t.config.RedactedQueryParams)
+	peer := req.URL.Hostname()
+
+	ctx, span := t.tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPMethodKey.String(req.Method),
+			semconv.HTTPURLKey.String(url),
+			semconv.NetPeerNameKey.String(peer),
+		),
+	)
+	defer span.End()
+
+	if t.config.Egress != nil {
+		if err := t.config.Egress.checkURL(req.URL); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			logging.FromContext(ctx).Warn("outbound request refused", slog.String("url", url), slog.String("error", err.Error()))
+			return nil, err
+		}
+	}
+
+	var breaker *Breaker
+	if t.config.Breakers != nil {
+		breaker = t.config.Breakers.forRequest(req)
+		if err := breaker.allow(time.Now()); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+	}
+
+	// RoundTrippers must not modify the request, so headers are set on a copy.
+	req = req.WithContext(ctx)
+	req.Header = req.Header.Clone()
+	if t.config.Propagator != nil {
+		t.config.Propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+	if id, ok := requestid.FromContext(ctx); ok && t.config.RequestIDHeader != "" && req.Header.Get(t.config.RequestIDHeader) == "" {
+		req.Header.Set(t.config.RequestIDHeader, id)
+	}
+
+	startTime := time.Now()
+	resp, err := t.send(req, span)
+	duration := time.Since(startTime)
+
+	if breaker != nil {
+		if err != nil && (ctx.Err() != nil || errors.Is(err, ErrEgressDenied)) {
+			breaker.release()
+		} else {
+			breaker.record(time.Now(), err != nil || resp.StatusCode >= http.StatusInternalServerError, duration)
+		}
+	}
+
+	if err != nil {
+		t.metrics.IncreaseRequestCounter(ctx, req.Method, peer, 0)
+		t.metrics.RecordRequestDuration(ctx, req.Method, peer, 0, duration)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logging.FromContext(ctx).Error("outbound request failed",
+			slog.String("method", req.Method),
+			slog.String("url", url),
+			slog.Duration("duration", duration),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	t.metrics.IncreaseRequestCounter(ctx, req.Method, peer, resp.StatusCode)
+	t.metrics.RecordRequestDuration(ctx, req.Method, peer, resp.StatusCode, duration)
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		logging.FromContext(ctx).Error("outbound request failed",
+			slog.String("method", req.Method),
+			slog.String("url", url),
+			slog.Duration("duration", duration),
+			slog.Int("status", resp.StatusCode),
+		)
+	}
+
+	if t.config.Egress != nil {
+		return t.config.Egress.limitResponse(resp)
+	}
+	return resp, nil
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/2n3g5c9/go-http/middlewares/requestid"
+)
+
+func TestTransport(t *testing.T) {
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Traceparent", r.Header.Get("Traceparent"))
+		w.Header().Set("X-Seen-Cloud-Trace", r.Header.Get("X-Cloud-Trace-Context"))
+		w.Header().Set("X-Seen-Request-Id", r.Header.Get(requestid.Header))
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		requestID  string
+		wantErr    bool
+		wantStatus int
+		wantCode   codes.Code
+	}{
+		{"Successful request", server.URL + "/ok", "req-1", false, http.StatusOK, codes.Unset},
+		{"Server error", server.URL + "/fail", "", false, http.StatusBadGateway, codes.Error},
+		{"Connection refused", closed.URL, "", true, 0, codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			client := New(NewConfig())
+
+			ctx := context.Background()
+			if tt.requestID != "" {
+				ctx = requestContext(tt.requestID)
+			}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, tt.url, nil)
+			require.NoError(t, err)
+			resp, err := client.Do(req)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			span := spans[0]
+			assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+			assert.Equal(t, tt.wantCode, span.Status().Code)
+			assert.Empty(t, req.Header, "the caller's request is left untouched")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Contains(t, resp.Header.Get("X-Seen-Traceparent"), span.SpanContext().TraceID().String())
+			assert.Contains(t, resp.Header.Get("X-Seen-Cloud-Trace"), span.SpanContext().TraceID().String())
+			assert.Equal(t, tt.requestID, resp.Header.Get("X-Seen-Request-Id"))
+			assert.Contains(t, span.Attributes(), semconv.HTTPStatusCodeKey.Int(tt.wantStatus))
+		})
+	}
+}
+
+// requestContext returns the context the request ID middleware gives to handlers of a request with the ID.
+func requestContext(id string) context.Context {
+	var ctx context.Context
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(requestid.Header, id)
+	requestid.Middleware(requestid.NewConfig())(handler).ServeHTTP(httptest.NewRecorder(), req)
+	return ctx
+}
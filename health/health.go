@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// Checker checks a dependency of the service, returning an error when it isn't usable.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc is a function implementing Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker.
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Config is a struct that holds configuration options for the readiness handler.
+type Config struct {
+	Checkers map[string]Checker // Checkers by name, all passing for the service to be ready.
+	Timeout  time.Duration      // Timeout of each check.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		Checkers: map[string]Checker{},
+		Timeout:  time.Second,
+	}
+}
+
+// Handler is the readiness handler function that takes a Config struct and returns the handler.
+// It runs the checkers concurrently and answers 200 when all pass, or a 503 problem naming the failing ones.
+func Handler(config *Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), config.Timeout)
+		defer cancel()
+
+		var (
+			mu     sync.Mutex
+			failed []string
+			wg     sync.WaitGroup
+		)
+		for name, checker := range config.Checkers {
+			wg.Add(1)
+			go func(name string, checker Checker) {
+				defer wg.Done()
+				if err := checker.Check(ctx); err != nil {
+					slog.Warn("readiness check failed", slog.String("check", name), slog.String("error", err.Error()))
+					mu.Lock()
+					failed = append(failed, name)
+					mu.Unlock()
+				}
+			}(name, checker)
+		}
+		wg.Wait()
+
+		if len(failed) > 0 {
+			sort.Strings(failed)
+			p := problem.New(http.StatusServiceUnavailable, "not ready")
+			for _, name := range failed {
+				p.Errors = append(p.Errors, problem.FieldError{Detail: name + " check failed"})
+			}
+			problem.Write(w, p)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	})
+}
@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+func TestHandler(t *testing.T) {
+	passing := CheckerFunc(func(ctx context.Context) error { return nil })
+	failing := CheckerFunc(func(ctx context.Context) error { return errors.New("unreachable") })
+
+	tests := []struct {
+		name       string
+		checkers   map[string]Checker
+		wantStatus int
+		wantErrors []problem.FieldError
+	}{
+		{"No checkers", map[string]Checker{}, http.StatusOK, nil},
+		{"All passing", map[string]Checker{"db": passing, "cache": passing}, http.StatusOK, nil},
+		{"Failing checks", map[string]Checker{"db": passing, "payments": failing, "billing": failing}, http.StatusServiceUnavailable,
+			[]problem.FieldError{{Detail: "billing check failed"}, {Detail: "payments check failed"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.Checkers = tt.checkers
+			rr := httptest.NewRecorder()
+
+			Handler(config).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantErrors != nil {
+				var got problem.Details
+				assert.NoError(t, json.NewDecoder(rr.Body).Decode(&got))
+				assert.Equal(t, tt.wantErrors, got.Errors)
+			}
+		})
+	}
+}
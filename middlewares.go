@@ -2,32 +2,268 @@ package go_http
 
 import (
 	"net/http"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth/apikey"
+	"github.com/2n3g5c9/go-http/middlewares/auth/jwt"
+	"github.com/2n3g5c9/go-http/middlewares/authz"
+	"github.com/2n3g5c9/go-http/middlewares/cache"
+	"github.com/2n3g5c9/go-http/middlewares/concurrency"
+	"github.com/2n3g5c9/go-http/middlewares/csrf"
+	"github.com/2n3g5c9/go-http/middlewares/idempotency"
+	"github.com/2n3g5c9/go-http/middlewares/ipfilter"
+	"github.com/2n3g5c9/go-http/middlewares/mirror"
+	"github.com/2n3g5c9/go-http/middlewares/negotiation"
+	"github.com/2n3g5c9/go-http/middlewares/ratelimit"
+	"github.com/2n3g5c9/go-http/middlewares/session"
 )
 
 // Middleware is a function type that represents an HTTP middleware.
 type Middleware func(http.Handler) http.Handler
 
 type middlewareOptions struct {
-	CORS      *CORSOption
-	Logging   *LoggingOption
-	Telemetry *TelemetryOption
+	APIKey      *APIKeyOption
+	Authz       *AuthzOption
+	BodyLimit   *BodyLimitOption
+	Cache       *CacheOption
+	Compress    *CompressOption
+	CORS        *CORSOption
+	CSRF        *CSRFOption
+	Concurrency *ConcurrencyOption
+	Decompress  *DecompressOption
+	Forwarded   *ForwardedOption
+	IAP         *GoogleIdentityOption
+	IDToken     *GoogleIdentityOption
+	Idempotency *IdempotencyOption
+	IPFilter    *IPFilterOption
+	JWT         *JWTOption
+	Logging     *LoggingOption
+	Mirror      *MirrorOption
+	Negotiation *NegotiationOption
+	OpenAPI     *OpenAPIOption
+	RateLimit   *RateLimitOption
+	RequestID   *RequestIDOption
+	Secure      *SecureOption
+	Session     *SessionOption
+	Telemetry   *TelemetryOption
 }
 
 type MiddlewareOption func(*middlewareOptions)
 
+type APIKeyOption struct {
+	Store            apikey.Store
+	Header           string
+	QueryParam       string
+	ExcludedPrefixes []string
+}
+
+type AuthzOption struct {
+	RoutePolicies map[string]authz.Policy
+	DefaultPolicy *authz.Policy
+}
+
+type BodyLimitOption struct {
+	DefaultLimit int64
+	RouteLimits  map[string]int64
+	Budget       int64
+	QueueTimeout time.Duration
+}
+
+type CacheOption struct {
+	MaxBytes         int64
+	ExcludedPrefixes []string
+	Store            cache.Store
+}
+
+type CompressOption struct {
+	MinSize      int
+	ContentTypes []string
+}
+
+type ConcurrencyOption struct {
+	Algorithm        concurrency.Algorithm
+	CriticalPrefixes []string
+	MaxQueue         int
+	QueueTimeout     time.Duration
+}
+
 type CORSOption struct {
 	AllowedMethods []string
 	AllowedOrigins []string
 }
 
+type CSRFOption struct {
+	ExcludedPrefixes []string
+	Secret           []byte
+	SessionID        csrf.SessionIDFunc
+}
+
+type DecompressOption struct {
+	MaxDecompressed int64
+}
+
+type ForwardedOption struct {
+	TrustedProxies []string
+	Headers        []string
+}
+
+type GoogleIdentityOption struct {
+	KeySource        jwt.KeySource
+	Audiences        []string
+	ExcludedPrefixes []string
+}
+
+type IdempotencyOption struct {
+	Required    bool
+	WaitTimeout time.Duration
+	Store       idempotency.Store
+}
+
+type IPFilterOption struct {
+	DefaultRule *ipfilter.Rule
+	RouteRules  map[string]ipfilter.Rule
+}
+
+type JWTOption struct {
+	KeySource        jwt.KeySource
+	Issuer           string
+	Audiences        []string
+	ExcludedPrefixes []string
+}
+
 type LoggingOption struct {
 	ExcludedPrefixes []string
 }
 
+type MirrorOption struct {
+	Target       string
+	DefaultRatio float64
+	RouteRatios  map[string]float64
+	Diff         mirror.DiffFunc
+}
+
+type NegotiationOption struct {
+	Registry         *negotiation.Registry
+	ExcludedPrefixes []string
+}
+
+type OpenAPIOption struct {
+	Document          *openapi3.T
+	ValidateResponses bool
+}
+
+type RateLimitOption struct {
+	DefaultLimit  *ratelimit.Limit
+	RouteLimits   map[string]ratelimit.Limit
+	KeyFunc       ratelimit.KeyFunc
+	Store         ratelimit.Store
+	FailurePolicy ratelimit.FailurePolicy
+}
+
+type RequestIDOption struct {
+	Header string
+}
+
+type SecureOption struct {
+	ContentSecurityPolicy string
+	RoutePolicies         map[string]string
+	ReportOnly            bool
+}
+
+type SessionOption struct {
+	Keys  [][]byte
+	Store session.Store
+}
+
 type TelemetryOption struct {
 	ExcludedPrefixes []string
 }
 
+// WithAPIKey returns a MiddlewareOption that requires an API key found in store, such as apikey.NewFileStore.
+// The key is read from the header, or from queryParam if not empty, and is redacted from logged URLs.
+// Paths with an excluded prefix opt out.
+func WithAPIKey(store apikey.Store, header, queryParam string, excludedPrefixes []string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.APIKey = &APIKeyOption{
+			Store:            store,
+			Header:           header,
+			QueryParam:       queryParam,
+			ExcludedPrefixes: excludedPrefixes,
+		}
+	}
+}
+
+// WithAuthorization returns a MiddlewareOption that enforces authorization policies on routes and groups,
+// keyed by path prefix optionally preceded by a method, such as "POST /orders". Unmatched routes use
+// defaultPolicy, or are allowed when it is nil. It relies on an authentication option providing the identity.
+func WithAuthorization(routePolicies map[string]authz.Policy, defaultPolicy *authz.Policy) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.Authz = &AuthzOption{
+			RoutePolicies: routePolicies,
+			DefaultPolicy: defaultPolicy,
+		}
+	}
+}
+
+// WithBodyLimit returns a MiddlewareOption that limits request body sizes, with optional per path prefix limits.
+func WithBodyLimit(defaultLimit int64, routeLimits map[string]int64) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.BodyLimit == nil {
+			opts.BodyLimit = &BodyLimitOption{}
+		}
+		opts.BodyLimit.DefaultLimit = defaultLimit
+		opts.BodyLimit.RouteLimits = routeLimits
+	}
+}
+
+// WithBodyBudget returns a MiddlewareOption that bounds the in-flight request body bytes across all requests.
+// Requests exceeding the budget wait up to queueTimeout before being rejected.
+func WithBodyBudget(budget int64, queueTimeout time.Duration) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.BodyLimit == nil {
+			opts.BodyLimit = &BodyLimitOption{}
+		}
+		opts.BodyLimit.Budget = budget
+		opts.BodyLimit.QueueTimeout = queueTimeout
+	}
+}
+
+// WithCache returns a MiddlewareOption that caches GET and HEAD responses according to their Cache-Control header,
+// in memory up to maxBytes, and answers conditional requests with 304. Paths with an excluded prefix opt out.
+func WithCache(maxBytes int64, excludedPrefixes []string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.Cache == nil {
+			opts.Cache = &CacheOption{}
+		}
+		opts.Cache.MaxBytes = maxBytes
+		opts.Cache.ExcludedPrefixes = excludedPrefixes
+	}
+}
+
+// WithCacheStore returns a MiddlewareOption that sets the storage backend of the cache middleware,
+// instead of the in-memory LRU store.
+func WithCacheStore(store cache.Store) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.Cache == nil {
+			opts.Cache = &CacheOption{}
+		}
+		opts.Cache.Store = store
+	}
+}
+
+// WithCompression returns a MiddlewareOption that sets the response compression middleware options.
+// Responses smaller than minSize or with a content type missing from contentTypes are sent uncompressed.
+func WithCompression(minSize int, contentTypes []string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.Compress = &CompressOption{
+			MinSize:      minSize,
+			ContentTypes: contentTypes,
+		}
+	}
+}
+
 // WithCORS returns a MiddlewareOption that sets the CORS middleware options.
 func WithCORS(allowedMethods, allowedOrigins []string) MiddlewareOption {
 	return func(opts *middlewareOptions) {
@@ -38,6 +274,163 @@ func WithCORS(allowedMethods, allowedOrigins []string) MiddlewareOption {
 	}
 }
 
+// WithCSRF returns a MiddlewareOption that protects unsafe requests against cross-site request forgery.
+// Cross-site requests are only accepted from the origins passed to WithCORS. Browsers without Fetch Metadata
+// support must submit the token returned by csrf.Token, checked against a cookie unless WithCSRFSessionTokens is used.
+// Requests authenticated with an API key and paths with an excluded prefix are exempt.
+func WithCSRF(excludedPrefixes []string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.CSRF == nil {
+			opts.CSRF = &CSRFOption{}
+		}
+		opts.CSRF.ExcludedPrefixes = excludedPrefixes
+	}
+}
+
+// WithCSRFSessionTokens returns a MiddlewareOption that makes the CSRF middleware use synchronizer tokens,
+// derived with secret from the session ID returned by sessionID, instead of double-submit cookies.
+func WithCSRFSessionTokens(secret []byte, sessionID csrf.SessionIDFunc) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.CSRF == nil {
+			opts.CSRF = &CSRFOption{}
+		}
+		opts.CSRF.Secret = secret
+		opts.CSRF.SessionID = sessionID
+	}
+}
+
+// WithConcurrencyLimit returns a MiddlewareOption that limits concurrent in-flight requests with an adaptive algorithm,
+// such as concurrency.NewAIMD or concurrency.NewGradient. Paths with one of the critical prefixes are never shed.
+func WithConcurrencyLimit(algorithm concurrency.Algorithm, criticalPrefixes []string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.Concurrency == nil {
+			opts.Concurrency = &ConcurrencyOption{}
+		}
+		opts.Concurrency.Algorithm = algorithm
+		opts.Concurrency.CriticalPrefixes = criticalPrefixes
+	}
+}
+
+// WithConcurrencyQueue returns a MiddlewareOption that lets up to maxQueue requests wait up to queueTimeout
+// for a slot when the concurrency limit is reached, instead of being shed right away.
+func WithConcurrencyQueue(maxQueue int, queueTimeout time.Duration) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.Concurrency == nil {
+			opts.Concurrency = &ConcurrencyOption{}
+		}
+		opts.Concurrency.MaxQueue = maxQueue
+		opts.Concurrency.QueueTimeout = queueTimeout
+	}
+}
+
+// WithDecompression returns a MiddlewareOption that decodes compressed request bodies.
+// Decoded bodies larger than maxDecompressed bytes are rejected to protect against decompression bombs.
+func WithDecompression(maxDecompressed int64) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.Decompress = &DecompressOption{
+			MaxDecompressed: maxDecompressed,
+		}
+	}
+}
+
+// WithTrustedProxies returns a MiddlewareOption that resolves the client IP address, scheme and host from the
+// forwarding headers set by the trusted proxies, given as CIDRs or IP addresses such as forwarded.GoogleFrontEnds.
+// The resolved client IP address is used for logging, telemetry and rate limiting.
+func WithTrustedProxies(trustedProxies []string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.Forwarded == nil {
+			opts.Forwarded = &ForwardedOption{}
+		}
+		opts.Forwarded.TrustedProxies = trustedProxies
+	}
+}
+
+// WithForwardingHeaders returns a MiddlewareOption that sets the headers carrying the client address, by order of
+// preference, such as forwarded.HeaderXForwardedFor. It defaults to Forwarded, X-Forwarded-For then X-Real-IP.
+func WithForwardingHeaders(headers []string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.Forwarded == nil {
+			opts.Forwarded = &ForwardedOption{}
+		}
+		opts.Forwarded.Headers = headers
+	}
+}
+
+// WithIAP returns a MiddlewareOption that requires a valid Identity-Aware Proxy assertion for the audience,
+// such as "/projects/NUMBER/global/backendServices/ID", which is required. A nil keySource uses Google's
+// published keys. Paths with an excluded prefix opt out.
+func WithIAP(keySource jwt.KeySource, audience string, excludedPrefixes []string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.IAP = &GoogleIdentityOption{
+			KeySource:        keySource,
+			Audiences:        []string{audience},
+			ExcludedPrefixes: excludedPrefixes,
+		}
+	}
+}
+
+// WithGoogleIDToken returns a MiddlewareOption that requires a valid Google-signed OIDC ID bearer token for one
+// of the audiences, such as the Cloud Run service URL, which are required. A nil keySource uses Google's published
+// keys. Paths with an excluded prefix opt out.
+func WithGoogleIDToken(keySource jwt.KeySource, audiences []string, excludedPrefixes []string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.IDToken = &GoogleIdentityOption{
+			KeySource:        keySource,
+			Audiences:        audiences,
+			ExcludedPrefixes: excludedPrefixes,
+		}
+	}
+}
+
+// WithIdempotency returns a MiddlewareOption that supports the Idempotency-Key header on POST and PATCH requests,
+// replaying the first response to retries with the same key. With required, requests without a key are rejected.
+// Duplicates sent while the first request is processed wait up to waitTimeout for its response, or get a 409.
+func WithIdempotency(required bool, waitTimeout time.Duration) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.Idempotency == nil {
+			opts.Idempotency = &IdempotencyOption{}
+		}
+		opts.Idempotency.Required = required
+		opts.Idempotency.WaitTimeout = waitTimeout
+	}
+}
+
+// WithIdempotencyStore returns a MiddlewareOption that sets the storage backend of the idempotency middleware,
+// such as an idempotency.RedisStore to share keys between instances.
+func WithIdempotencyStore(store idempotency.Store) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.Idempotency == nil {
+			opts.Idempotency = &IdempotencyOption{}
+		}
+		opts.Idempotency.Store = store
+	}
+}
+
+// WithIPFilter returns a MiddlewareOption that allows or denies requests by client IP address, with defaultRule
+// applying to paths without a rule in routeRules, keyed by path prefix. Rules use sets such as ipfilter.NewSet,
+// or ipfilter.NewFileSet to reload them without a restart.
+func WithIPFilter(defaultRule *ipfilter.Rule, routeRules map[string]ipfilter.Rule) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.IPFilter = &IPFilterOption{
+			DefaultRule: defaultRule,
+			RouteRules:  routeRules,
+		}
+	}
+}
+
+// WithJWT returns a MiddlewareOption that requires a valid JWT bearer token signed by a key from keySource,
+// such as jwt.NewRemoteJWKS, issued by issuer for one of the audiences. Paths with an excluded prefix opt out.
+func WithJWT(keySource jwt.KeySource, issuer string, audiences []string, excludedPrefixes []string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.JWT = &JWTOption{
+			KeySource:        keySource,
+			Issuer:           issuer,
+			Audiences:        audiences,
+			ExcludedPrefixes: excludedPrefixes,
+		}
+	}
+}
+
 // WithLogging returns a MiddlewareOption that sets the Logging middleware options.
 func WithLogging(prefixes []string) MiddlewareOption {
 	return func(opts *middlewareOptions) {
@@ -47,6 +440,108 @@ func WithLogging(prefixes []string) MiddlewareOption {
 	}
 }
 
+// WithMirror returns a MiddlewareOption that copies a sampled share of requests to the shadow backend at target,
+// such as a new version of the service. Shares are set per path prefix by routeRatios, others use defaultRatio.
+// With diff, such as mirror.DiffStatusAndBody, mismatches between primary and shadow responses are logged.
+func WithMirror(target string, defaultRatio float64, routeRatios map[string]float64, diff mirror.DiffFunc) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.Mirror = &MirrorOption{
+			Target:       target,
+			DefaultRatio: defaultRatio,
+			RouteRatios:  routeRatios,
+			Diff:         diff,
+		}
+	}
+}
+
+// WithContentNegotiation returns a MiddlewareOption that negotiates the media type of responses written with
+// negotiation.Respond and checks the media type of request bodies, from the codecs of registry.
+// A nil registry uses negotiation.DefaultRegistry. Paths with an excluded prefix, such as static files, opt out.
+func WithContentNegotiation(registry *negotiation.Registry, excludedPrefixes []string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.Negotiation = &NegotiationOption{
+			Registry:         registry,
+			ExcludedPrefixes: excludedPrefixes,
+		}
+	}
+}
+
+// WithOpenAPI returns a MiddlewareOption that validates requests against the operations of an OpenAPI 3 document,
+// such as returned by openapi.Load. With validateResponses, meant for tests, responses are validated too.
+func WithOpenAPI(document *openapi3.T, validateResponses bool) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.OpenAPI = &OpenAPIOption{
+			Document:          document,
+			ValidateResponses: validateResponses,
+		}
+	}
+}
+
+// WithRateLimit returns a MiddlewareOption that sets the rate limit middleware options.
+// Requests are counted per key returned by keyFunc, defaulting to the client IP when nil.
+// NewRouter panics when a limit has no positive Requests and Window.
+func WithRateLimit(defaultLimit *ratelimit.Limit, routeLimits map[string]ratelimit.Limit, keyFunc ratelimit.KeyFunc) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.RateLimit == nil {
+			opts.RateLimit = &RateLimitOption{}
+		}
+		opts.RateLimit.DefaultLimit = defaultLimit
+		opts.RateLimit.RouteLimits = routeLimits
+		opts.RateLimit.KeyFunc = keyFunc
+	}
+}
+
+// WithRateLimitStore returns a MiddlewareOption that sets the storage backend of the rate limit middleware,
+// such as a ratelimit.RedisStore to share limits between instances.
+// The failurePolicy decides whether requests are allowed or rejected when the store cannot be reached.
+func WithRateLimitStore(store ratelimit.Store, failurePolicy ratelimit.FailurePolicy) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		if opts.RateLimit == nil {
+			opts.RateLimit = &RateLimitOption{}
+		}
+		opts.RateLimit.Store = store
+		opts.RateLimit.FailurePolicy = failurePolicy
+	}
+}
+
+// WithRequestID returns a MiddlewareOption that gives each request an ID, kept from the header when sent by
+// clients or proxies, and echoed in the response. An empty header means requestid.Header. The ID is added to
+// request logs and propagated to outbound requests sent with a client.Transport.
+func WithRequestID(header string) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.RequestID = &RequestIDOption{
+			Header: header,
+		}
+	}
+}
+
+// WithSecurityHeaders returns a MiddlewareOption that sets security response headers, such as HSTS and
+// Content-Security-Policy. The policy, overridden per path prefix by routePolicies, may contain secure.NoncePlaceholder,
+// replaced by a fresh nonce available with secure.Nonce. An empty policy keeps the default one.
+// With reportOnly, policy violations are only reported, which helps rolling out new policies.
+func WithSecurityHeaders(contentSecurityPolicy string, routePolicies map[string]string, reportOnly bool) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.Secure = &SecureOption{
+			ContentSecurityPolicy: contentSecurityPolicy,
+			RoutePolicies:         routePolicies,
+			ReportOnly:            reportOnly,
+		}
+	}
+}
+
+// WithSessions returns a MiddlewareOption that provides sessions stored in encrypted cookies, available with
+// session.FromContext. The first of keys encrypts, while all decrypt to allow rotation. A non-nil store, such as
+// session.NewFileStore, keeps the data server-side. Pass session.SessionID to WithCSRFSessionTokens to bind CSRF
+// tokens to sessions.
+func WithSessions(keys [][]byte, store session.Store) MiddlewareOption {
+	return func(opts *middlewareOptions) {
+		opts.Session = &SessionOption{
+			Keys:  keys,
+			Store: store,
+		}
+	}
+}
+
 // WithTelemetry returns a MiddlewareOption that sets the Telemetry (Metrics & Traces) middleware options.
 func WithTelemetry(excludedPrefixes []string) MiddlewareOption {
 	return func(opts *middlewareOptions) {
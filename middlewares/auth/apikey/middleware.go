@@ -0,0 +1,135 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// Config is a struct that holds configuration options for the API key middleware.
+type Config struct {
+	Store            Store               // Storage backend of the keys.
+	Header           string              // Header carrying the key.
+	QueryParam       string              // Query parameter carrying the key, empty disables it.
+	RouteScopes      map[string][]string // Scopes a key must have per path prefix.
+	ExcludedPrefixes []string            // Path prefixes that don't require a key.
+}
+
+// NewConfig creates a new Config struct with default values.
+// The Store must be set, for instance with NewMemoryStore or NewFileStore.
+func NewConfig() *Config {
+	return &Config{
+		Store:            nil,
+		Header:           "X-API-Key",
+		QueryParam:       "",
+		RouteScopes:      map[string][]string{},
+		ExcludedPrefixes: []string{},
+	}
+}
+
+// Method is the auth.Identity method of callers authenticated with an API key.
+const Method = "apikey"
+
+type keyKey struct{}
+
+// KeyFromContext returns the API key the request was authenticated with.
+func KeyFromContext(ctx context.Context) (*Key, bool) {
+	key, ok := ctx.Value(keyKey{}).(*Key)
+	return key, ok
+}
+
+// Middleware is the API key authentication middleware function that takes a Config struct and returns the middleware.
+// The key is available with KeyFromContext and the caller with auth.IdentityFromContext.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			raw := extract(r, config)
+			if raw == "" {
+				problem.Error(w, http.StatusUnauthorized, "missing API key")
+				return
+			}
+
+			key, err := config.Store.Lookup(r.Context(), HashKey(raw))
+			switch {
+			case errors.Is(err, ErrUnknownKey):
+				slog.Error("API key rejected", slog.String("path", r.URL.Path))
+				problem.Error(w, http.StatusUnauthorized, "invalid API key")
+				return
+			case err != nil:
+				slog.Error("API key lookup failed", slog.String("error", err.Error()))
+				problem.Error(w, http.StatusServiceUnavailable, "API key verification unavailable")
+				return
+			case key.Expired(time.Now()):
+				slog.Error("expired API key rejected", slog.String("keyId", key.ID))
+				problem.Error(w, http.StatusUnauthorized, "expired API key")
+				return
+			}
+
+			if _, required, ok := common.MatchPrefix(r.URL.Path, config.RouteScopes); ok && !hasScopes(key.Scopes, required) {
+				slog.Error("API key lacks scopes", slog.String("keyId", key.ID), slog.Any("required", required))
+				problem.Error(w, http.StatusForbidden, "API key lacks required scopes "+strings.Join(required, ", "))
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), keyKey{}, key))
+			if config.QueryParam != "" {
+				stripQueryParam(r, config.QueryParam)
+			}
+			r = auth.WithIdentity(r, &auth.Identity{
+				Subject: key.Subject,
+				Method:  Method,
+				Scopes:  key.Scopes,
+			})
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// extract returns the API key carried by the request header, or its query parameter.
+func extract(r *http.Request, config *Config) string {
+	if key := r.Header.Get(config.Header); key != "" {
+		return key
+	}
+	if config.QueryParam != "" {
+		return r.URL.Query().Get(config.QueryParam)
+	}
+	return ""
+}
+
+// hasScopes checks if all required scopes are granted.
+func hasScopes(granted, required []string) bool {
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripQueryParam removes the query parameter from the request URL so the key doesn't travel further.
+func stripQueryParam(r *http.Request, name string) {
+	query := r.URL.Query()
+	if !query.Has(name) {
+		return
+	}
+
+	query.Del(name)
+	u := *r.URL
+	u.RawQuery = query.Encode()
+	r.URL = &u
+	r.RequestURI = u.RequestURI()
+}
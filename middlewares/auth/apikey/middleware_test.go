@@ -0,0 +1,66 @@
+package apikey
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+)
+
+func TestMiddleware(t *testing.T) {
+	config := NewConfig()
+	config.Store = NewMemoryStore(
+		Key{ID: "k1", Hash: HashKey("valid"), Subject: "billing", Scopes: []string{"invoices:read"}},
+		Key{ID: "k2", Hash: HashKey("expired"), Subject: "legacy", ExpiresAt: time.Now().Add(-time.Hour)},
+	)
+	config.QueryParam = "api_key"
+	config.RouteScopes = map[string][]string{"/invoices": {"invoices:read"}, "/admin": {"admin"}}
+	config.ExcludedPrefixes = []string{"/health"}
+
+	tests := []struct {
+		name        string
+		target      string
+		header      string
+		wantStatus  int
+		wantSubject string
+	}{
+		{"Valid header key", "/invoices", "valid", http.StatusOK, "billing"},
+		{"Valid query key", "/invoices?api_key=valid&page=2", "", http.StatusOK, "billing"},
+		{"Missing key", "/invoices", "", http.StatusUnauthorized, ""},
+		{"Unknown key", "/invoices", "unknown", http.StatusUnauthorized, ""},
+		{"Expired key", "/invoices", "expired", http.StatusUnauthorized, ""},
+		{"Missing scope", "/admin", "valid", http.StatusForbidden, ""},
+		{"Excluded path", "/health", "", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSubject string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+					gotSubject = identity.Subject
+				}
+				if key, ok := KeyFromContext(r.Context()); ok {
+					assert.Equal(t, "k1", key.ID)
+				}
+				// The key never travels past the middleware in the URL.
+				assert.NotContains(t, r.URL.String(), "valid")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("X-API-Key", tt.header)
+			}
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantSubject, gotSubject)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned when no stored key matches.
+var ErrUnknownKey = errors.New("unknown API key")
+
+// Key is a stored API key. Only the hash of the key is kept.
+type Key struct {
+	ID        string    `json:"id"`        // Public identifier of the key, safe to log.
+	Hash      string    `json:"hash"`      // Hex-encoded SHA-256 hash of the key, see HashKey.
+	Subject   string    `json:"subject"`   // Identity of the caller owning the key.
+	Scopes    []string  `json:"scopes"`    // Scopes granted to the key.
+	ExpiresAt time.Time `json:"expiresAt"` // Expiry of the key, zero means it never expires.
+}
+
+// Expired checks if the key is expired at the given time.
+func (k *Key) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// HashKey returns the hex-encoded SHA-256 hash of an API key, as stored in Key.Hash.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Store is a storage backend for API keys.
+type Store interface {
+	// Lookup returns the key with the given hash, or ErrUnknownKey.
+	Lookup(ctx context.Context, hash string) (*Key, error)
+}
+
+// MemoryStore is a Store keeping keys in process memory.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	keys []Key
+}
+
+// NewMemoryStore returns a new MemoryStore holding the given keys.
+func NewMemoryStore(keys ...Key) *MemoryStore {
+	return &MemoryStore{keys: keys}
+}
+
+// Add adds a key to the store.
+func (s *MemoryStore) Add(key Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, key)
+}
+
+// Lookup implements Store.
+func (s *MemoryStore) Lookup(_ context.Context, hash string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return find(s.keys, hash)
+}
+
+// FileStore is a Store reading keys from a JSON file holding an array of Key, reloaded when it changes.
+type FileStore struct {
+	path          string
+	checkInterval time.Duration
+
+	mu        sync.RWMutex
+	keys      []Key
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewFileStore returns a new FileStore reading the given file, checked for changes every checkInterval.
+func NewFileStore(path string, checkInterval time.Duration) (*FileStore, error) {
+	s := &FileStore{path: path, checkInterval: checkInterval}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Lookup implements Store.
+func (s *FileStore) Lookup(_ context.Context, hash string) (*Key, error) {
+	s.mu.RLock()
+	stale := time.Since(s.checkedAt) > s.checkInterval
+	s.mu.RUnlock()
+
+	if stale {
+		// Keep serving the previous keys if the file became unreadable.
+		_ = s.reload()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return find(s.keys, hash)
+}
+
+// reload reads the file again if it was modified since the last read.
+func (s *FileStore) reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkedAt = time.Now()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	var keys []Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("parse API keys file %s: %w", s.path, err)
+	}
+
+	s.keys, s.modTime = keys, info.ModTime()
+	return nil
+}
+
+// find returns the key with the given hash, comparing against every key in constant time.
+func find(keys []Key, hash string) (*Key, error) {
+	var found *Key
+
+	for i := range keys {
+		// Don't stop at the first match so the lookup time doesn't depend on the key's position.
+		if subtle.ConstantTimeCompare([]byte(keys[i].Hash), []byte(hash)) == 1 {
+			found = &keys[i]
+		}
+	}
+
+	if found == nil {
+		return nil, ErrUnknownKey
+	}
+	key := *found
+	return &key, nil
+}
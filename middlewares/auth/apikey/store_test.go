@@ -0,0 +1,71 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(Key{ID: "k1", Hash: HashKey("secret-1"), Subject: "billing"})
+	store.Add(Key{ID: "k2", Hash: HashKey("secret-2"), Subject: "orders"})
+
+	tests := []struct {
+		name        string
+		key         string
+		wantSubject string
+		wantErr     error
+	}{
+		{"First key", "secret-1", "billing", nil},
+		{"Added key", "secret-2", "orders", nil},
+		{"Unknown key", "secret-3", "", ErrUnknownKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Lookup(context.Background(), HashKey(tt.key))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSubject, got.Subject)
+		})
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	write := func(keys []Key, modTime time.Time) {
+		data, err := json.Marshal(keys)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(path, data, 0o600))
+		assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	write([]Key{{ID: "k1", Hash: HashKey("secret-1"), Subject: "billing"}}, time.Unix(1000, 0))
+
+	store, err := NewFileStore(path, 0)
+	assert.NoError(t, err)
+
+	got, err := store.Lookup(context.Background(), HashKey("secret-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "billing", got.Subject)
+
+	// Changes to the file are picked up without a restart.
+	write([]Key{{ID: "k2", Hash: HashKey("secret-2"), Subject: "orders"}}, time.Unix(2000, 0))
+
+	_, err = store.Lookup(context.Background(), HashKey("secret-1"))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	got, err = store.Lookup(context.Background(), HashKey("secret-2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", got.Subject)
+
+	_, err = NewFileStore(filepath.Join(t.TempDir(), "missing.json"), time.Minute)
+	assert.Error(t, err)
+}
@@ -0,0 +1,147 @@
+package google
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+	"github.com/2n3g5c9/go-http/middlewares/auth/jwt"
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+const (
+	// IAPHeader is the header carrying the Identity-Aware Proxy assertion.
+	IAPHeader = "X-Goog-IAP-JWT-Assertion"
+	// IAPKeysURL is the JWK set Identity-Aware Proxy assertions are signed with.
+	IAPKeysURL = "https://www.gstatic.com/iap/verify/public_key-jwk"
+	// IAPIssuer is the issuer of Identity-Aware Proxy assertions.
+	IAPIssuer = "https://cloud.google.com/iap"
+
+	// IDTokenKeysURL is the JWK set Google-signed OIDC ID tokens are signed with.
+	IDTokenKeysURL = "https://www.googleapis.com/oauth2/v3/certs"
+)
+
+// IDTokenIssuers are the issuers of Google-signed OIDC ID tokens.
+var IDTokenIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+// Identity methods of callers authenticated by the middlewares.
+const (
+	MethodIAP     = "iap"
+	MethodIDToken = "google_id_token"
+)
+
+// Config is a struct that holds configuration options for the Google identity middlewares.
+type Config struct {
+	Audiences        []string      // Expected audiences, such as "/projects/NUMBER/global/backendServices/ID" for IAP or the Cloud Run service URL for ID tokens.
+	KeySource        jwt.KeySource // Source of Google's public keys.
+	ClockSkew        time.Duration // Tolerance applied to token validity times.
+	ExcludedPrefixes []string      // Path prefixes that don't require a token.
+}
+
+// NewIAPConfig creates a new Config struct for Identity-Aware Proxy assertions with default values.
+func NewIAPConfig() *Config {
+	return &Config{
+		Audiences:        []string{},
+		KeySource:        jwt.NewRemoteJWKS(IAPKeysURL, nil),
+		ClockSkew:        30 * time.Second,
+		ExcludedPrefixes: []string{},
+	}
+}
+
+// NewIDTokenConfig creates a new Config struct for Google-signed OIDC ID tokens with default values.
+func NewIDTokenConfig() *Config {
+	return &Config{
+		Audiences:        []string{},
+		KeySource:        jwt.NewRemoteJWKS(IDTokenKeysURL, nil),
+		ClockSkew:        30 * time.Second,
+		ExcludedPrefixes: []string{},
+	}
+}
+
+// IAPMiddleware is the middleware function verifying Identity-Aware Proxy assertions that takes a Config struct.
+// It panics without audiences, as assertions minted for any other backend service would be accepted.
+func IAPMiddleware(config *Config) func(http.Handler) http.Handler {
+	requireAudiences(config, "IAP assertions")
+
+	verifier := jwt.NewVerifier(jwt.VerifierConfig{
+		KeySource:  config.KeySource,
+		Algorithms: []string{"ES256"},
+		Issuers:    []string{IAPIssuer},
+		Audiences:  config.Audiences,
+		ClockSkew:  config.ClockSkew,
+	})
+
+	return middleware(config, verifier, MethodIAP, func(r *http.Request) (string, error) {
+		if assertion := r.Header.Get(IAPHeader); assertion != "" {
+			return assertion, nil
+		}
+		return "", jwt.ErrMissingToken
+	})
+}
+
+// IDTokenMiddleware is the middleware function verifying Google-signed OIDC ID bearer tokens, as sent to Cloud Run
+// services requiring authenticated invocations, that takes a Config struct. It panics without audiences, as
+// anyone with a Google account can get an ID token for any other audience.
+func IDTokenMiddleware(config *Config) func(http.Handler) http.Handler {
+	requireAudiences(config, "ID tokens")
+
+	verifier := jwt.NewVerifier(jwt.VerifierConfig{
+		KeySource:  config.KeySource,
+		Algorithms: []string{"RS256"},
+		Issuers:    IDTokenIssuers,
+		Audiences:  config.Audiences,
+		ClockSkew:  config.ClockSkew,
+	})
+
+	return middleware(config, verifier, MethodIDToken, jwt.BearerToken)
+}
+
+// requireAudiences panics if the config has no audiences or an empty one.
+func requireAudiences(config *Config, tokens string) {
+	if len(config.Audiences) == 0 || slices.Contains(config.Audiences, "") {
+		panic("google: " + tokens + " require non-empty audiences")
+	}
+}
+
+// middleware returns a middleware verifying the token extracted from requests.
+func middleware(config *Config, verifier *jwt.Verifier, method string, extract func(*http.Request) (string, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, err := extract(r)
+			if err == nil {
+				var claims *jwt.Claims
+				if claims, err = verifier.Verify(r.Context(), token); err == nil {
+					r = r.WithContext(jwt.WithClaims(r.Context(), claims))
+					r = auth.WithIdentity(r, &auth.Identity{
+						Subject: claims.Subject,
+						Email:   claims.Email,
+						Method:  method,
+					})
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			slog.Error("Google identity rejected",
+				slog.String("method", method),
+				slog.String("path", r.URL.Path),
+				slog.String("error", err.Error()),
+			)
+			if errors.Is(err, jwt.ErrKeySource) {
+				problem.Error(w, http.StatusServiceUnavailable, jwt.ErrKeySource.Error())
+				return
+			}
+			problem.Error(w, http.StatusUnauthorized, jwt.InvalidTokenDetail)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package google
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+	"github.com/2n3g5c9/go-http/middlewares/auth/jwt"
+)
+
+// localKeySet writes a JWK set with the public keys to a file and returns a key source reading it.
+func localKeySet(t *testing.T, ecKey *ecdsa.PrivateKey, rsaKey *rsa.PrivateKey) jwt.KeySource {
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+
+	data, err := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "EC", "kid": "iap", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+		{"kty": "RSA", "kid": "oidc", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+	}})
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+	return jwt.NewFileJWKS(path)
+}
+
+// sign returns an ES256 or RS256 token with the claims.
+func sign(t *testing.T, kid string, key crypto.Signer, claims map[string]any) string {
+	alg := "RS256"
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		alg = "ES256"
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		assert.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestMiddlewares(t *testing.T) {
+	var (
+		ecKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+		keys      = localKeySet(t, ecKey, rsaKey)
+		exp       = time.Now().Add(time.Hour).Unix()
+		iapAud    = "/projects/123/global/backendServices/456"
+		runAud    = "https://service-abc.a.run.app"
+		claims    = func(iss, aud string) map[string]any {
+			return map[string]any{"iss": iss, "aud": aud, "sub": "accounts.google.com:42", "email": "alice@example.com", "exp": exp}
+		}
+	)
+
+	iapConfig := NewIAPConfig()
+	iapConfig.KeySource = keys
+	iapConfig.Audiences = []string{iapAud}
+
+	idTokenConfig := NewIDTokenConfig()
+	idTokenConfig.KeySource = keys
+	idTokenConfig.Audiences = []string{runAud}
+
+	tests := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+		header     string
+		value      string
+		wantStatus int
+		wantMethod string
+	}{
+		{"Valid IAP assertion", IAPMiddleware(iapConfig), IAPHeader, sign(t, "iap", ecKey, claims(IAPIssuer, iapAud)), http.StatusOK, MethodIAP},
+		{"IAP assertion for another backend", IAPMiddleware(iapConfig), IAPHeader, sign(t, "iap", ecKey, claims(IAPIssuer, "/projects/123/global/backendServices/789")), http.StatusUnauthorized, ""},
+		{"IAP assertion with wrong algorithm", IAPMiddleware(iapConfig), IAPHeader, sign(t, "oidc", rsaKey, claims(IAPIssuer, iapAud)), http.StatusUnauthorized, ""},
+		{"Missing IAP assertion", IAPMiddleware(iapConfig), "", "", http.StatusUnauthorized, ""},
+		{"Valid ID token", IDTokenMiddleware(idTokenConfig), "Authorization", "Bearer " + sign(t, "oidc", rsaKey, claims("https://accounts.google.com", runAud)), http.StatusOK, MethodIDToken},
+		{"ID token from another issuer", IDTokenMiddleware(idTokenConfig), "Authorization", "Bearer " + sign(t, "oidc", rsaKey, claims("https://evil.example", runAud)), http.StatusUnauthorized, ""},
+		{"ID token for another service", IDTokenMiddleware(idTokenConfig), "Authorization", "Bearer " + sign(t, "oidc", rsaKey, claims("accounts.google.com", "https://other.a.run.app")), http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var identity *auth.Identity
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identity, _ = auth.IdentityFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rr := httptest.NewRecorder()
+
+			tt.middleware(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code, fmt.Sprint(rr.Body))
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "alice@example.com", identity.Email)
+				assert.Equal(t, "accounts.google.com:42", identity.Subject)
+				assert.Equal(t, tt.wantMethod, identity.Method)
+			}
+		})
+	}
+}
+
+func TestMiddlewaresWithoutAudience(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *Config
+		middleware func(*Config) func(http.Handler) http.Handler
+		audiences  []string
+	}{
+		{"IAP without audience", NewIAPConfig(), IAPMiddleware, []string{}},
+		{"IAP with empty audience", NewIAPConfig(), IAPMiddleware, []string{""}},
+		{"ID token without audience", NewIDTokenConfig(), IDTokenMiddleware, nil},
+		{"ID token with empty audience", NewIDTokenConfig(), IDTokenMiddleware, []string{"https://service-abc.a.run.app", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Audiences = tt.audiences
+			assert.Panics(t, func() { tt.middleware(tt.config) })
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/logging"
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	Subject string   // Stable identifier of the caller.
+	Email   string   // Email address of the caller, if known.
+	Method  string   // Authentication method, such as "jwt".
+	Roles   []string // Roles granted to the caller.
+	Scopes  []string // Scopes granted to the caller.
+}
+
+type identityKey struct{}
+
+// IdentityFromContext returns the authenticated Identity carried by ctx.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
+
+// WithIdentity returns a copy of r carrying the identity, which is also added to the request logger and span.
+func WithIdentity(r *http.Request, identity *Identity) *http.Request {
+	ctx := context.WithValue(r.Context(), identityKey{}, identity)
+	ctx = logging.WithAttrs(ctx,
+		slog.String("subject", identity.Subject),
+		slog.String("authMethod", identity.Method),
+	)
+
+	trace.SpanFromContext(ctx).SetAttributes(semconv.EnduserIDKey.String(identity.Subject))
+
+	return r.WithContext(ctx)
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/logging"
+)
+
+func TestWithIdentity(t *testing.T) {
+	buf := new(bytes.Buffer)
+	slog.SetDefault(slog.New(slog.NewJSONHandler(buf, nil)))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, ok := IdentityFromContext(req.Context())
+	assert.False(t, ok)
+
+	req = WithIdentity(req, &Identity{Subject: "alice", Method: "jwt"})
+
+	identity, ok := IdentityFromContext(req.Context())
+	assert.True(t, ok)
+	assert.Equal(t, "alice", identity.Subject)
+
+	logging.FromContext(req.Context()).Info("handled")
+	assert.Contains(t, buf.String(), `"subject":"alice"`)
+	assert.Contains(t, buf.String(), `"authMethod":"jwt"`)
+}
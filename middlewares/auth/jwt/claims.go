@@ -0,0 +1,119 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Claims holds the claims of a verified token.
+type Claims struct {
+	Issuer    string         `json:"iss,omitempty"`
+	Subject   string         `json:"sub,omitempty"`
+	Audience  Audience       `json:"aud,omitempty"`
+	ExpiresAt *NumericDate   `json:"exp,omitempty"`
+	NotBefore *NumericDate   `json:"nbf,omitempty"`
+	IssuedAt  *NumericDate   `json:"iat,omitempty"`
+	ID        string         `json:"jti,omitempty"`
+	Email     string         `json:"email,omitempty"`
+	Raw       map[string]any `json:"-"` // All claims, including the registered ones.
+}
+
+// String returns the named claim if it is a string.
+func (c *Claims) String(name string) (string, bool) {
+	value, ok := c.Raw[name].(string)
+	return value, ok
+}
+
+// Strings returns the named claim if it is an array of strings.
+func (c *Claims) Strings(name string) ([]string, bool) {
+	values, ok := c.Raw[name].([]any)
+	if !ok {
+		return nil, false
+	}
+
+	strs := make([]string, 0, len(values))
+	for _, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		strs = append(strs, str)
+	}
+	return strs, true
+}
+
+// Scopes returns the scopes granted by the space-delimited "scope" claim or the "scp" array claim.
+func (c *Claims) Scopes() []string {
+	if scope, ok := c.String("scope"); ok {
+		return strings.Fields(scope)
+	}
+	scopes, _ := c.Strings("scp")
+	return scopes
+}
+
+// Roles returns the roles granted by the "roles" array claim.
+func (c *Claims) Roles() []string {
+	roles, _ := c.Strings("roles")
+	return roles
+}
+
+// Audience is the "aud" claim, which is either a single string or an array of strings.
+type Audience []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// Contains checks if the audience contains any of the given values.
+func (a Audience) Contains(values []string) bool {
+	for _, aud := range a {
+		for _, value := range values {
+			if aud == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NumericDate is a JSON numeric date, the number of seconds since the Unix epoch.
+type NumericDate struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *NumericDate) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return err
+	}
+	d.Time = time.Unix(0, int64(seconds*float64(time.Second)))
+	return nil
+}
+
+type claimsKey struct{}
+
+// ClaimsFromContext returns the verified Claims carried by ctx.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// WithClaims returns a copy of ctx carrying the claims.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
@@ -0,0 +1,165 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+)
+
+// ErrKeyNotFound is returned when no key matches the token's key ID.
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// refreshTimeout is the maximum duration of a key set fetch.
+const refreshTimeout = 10 * time.Second
+
+// KeySource provides the keys used to verify token signatures.
+type KeySource interface {
+	// Key returns the public key, or secret for HMAC, with the given key ID.
+	Key(ctx context.Context, kid string) (any, error)
+}
+
+// JWKS is a KeySource backed by a JSON Web Key Set, cached and refreshed on rotation.
+type JWKS struct {
+	fetch              func(ctx context.Context) ([]byte, error)
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]any
+	fetchedAt time.Time
+
+	refreshMu sync.Mutex
+	failedAt  time.Time
+}
+
+// NewJWKS returns a new JWKS loading the key set with fetch, at most every minRefreshInterval
+// when an unknown key ID shows up and at least every refreshInterval.
+func NewJWKS(fetch func(ctx context.Context) ([]byte, error), refreshInterval, minRefreshInterval time.Duration) *JWKS {
+	return &JWKS{
+		fetch:              fetch,
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: minRefreshInterval,
+	}
+}
+
+// NewRemoteJWKS returns a new JWKS fetched from the given URL, refreshed hourly.
+func NewRemoteJWKS(url string, client *http.Client) *JWKS {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return NewJWKS(func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch JWKS from %s: unexpected status %d", url, resp.StatusCode)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}, time.Hour, time.Minute)
+}
+
+// NewFileJWKS returns a new JWKS read from the given file, reloaded every minute.
+func NewFileJWKS(path string) *JWKS {
+	return NewJWKS(func(context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}, time.Minute, time.Minute)
+}
+
+// Key implements KeySource.
+func (s *JWKS) Key(ctx context.Context, kid string) (any, error) {
+	s.mu.RLock()
+	var (
+		keys      = s.keys
+		fetchedAt = s.fetchedAt
+	)
+	s.mu.RUnlock()
+
+	if keys == nil || time.Since(fetchedAt) > s.refreshInterval {
+		if err := s.refresh(ctx, fetchedAt); err != nil && keys == nil {
+			return nil, err
+		}
+	} else if _, ok := lookup(keys, kid); !ok && time.Since(fetchedAt) > s.minRefreshInterval {
+		// An unknown key ID may mean the keys were rotated.
+		_ = s.refresh(ctx, fetchedAt)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := lookup(s.keys, kid)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, kid)
+	}
+	return key, nil
+}
+
+// refresh reloads the key set, unless another caller already did since seenFetchedAt.
+// On failure, the previous keys are kept. The fetch outlives the caller's request, as other requests wait for it.
+func (s *JWKS) refresh(ctx context.Context, seenFetchedAt time.Time) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.mu.RLock()
+	alreadyRefreshed := s.fetchedAt.After(seenFetchedAt)
+	s.mu.RUnlock()
+	if alreadyRefreshed {
+		return nil
+	}
+
+	// Don't hammer an unavailable source, the previous keys keep being used meanwhile.
+	if time.Since(s.failedAt) < s.minRefreshInterval {
+		return errors.New("JWKS refresh throttled after a failure")
+	}
+
+	fetchCtx, cancel := context.WithTimeout(common.Detach(ctx), refreshTimeout)
+	defer cancel()
+
+	data, err := s.fetch(fetchCtx)
+	if err == nil {
+		var keys map[string]any
+		if keys, err = ParseJWKS(data); err == nil {
+			s.mu.Lock()
+			s.keys, s.fetchedAt = keys, time.Now()
+			s.mu.Unlock()
+			return nil
+		}
+	}
+
+	// A caller going away says nothing about the source.
+	if ctx.Err() == nil {
+		s.failedAt = time.Now()
+	}
+	slog.Error("JWKS refresh failed", slog.String("error", err.Error()))
+	return err
+}
+
+// lookup returns the key with the given ID, or the only key when the token has no key ID.
+func lookup(keys map[string]any, kid string) (any, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
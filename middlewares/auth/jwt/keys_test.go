@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// marshalJWKS returns a JSON Web Key Set with the public keys indexed by key ID.
+func marshalJWKS(t *testing.T, keys map[string]any) []byte {
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+
+	set := jwkSet{}
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "RSA", Kid: kid, N: encode(k.N), E: encode(big.NewInt(int64(k.E)))})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "EC", Kid: kid, Crv: k.Params().Name, X: encode(k.X), Y: encode(k.Y)})
+		}
+	}
+
+	data, err := json.Marshal(set)
+	assert.NoError(t, err)
+	return data
+}
+
+func TestParseJWKS(t *testing.T) {
+	var (
+		rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+		ecKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	)
+
+	keys, err := ParseJWKS(marshalJWKS(t, map[string]any{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}))
+	assert.NoError(t, err)
+	assert.True(t, rsaKey.PublicKey.Equal(keys["rsa"]))
+	assert.True(t, ecKey.PublicKey.Equal(keys["ec"]))
+
+	// Encryption keys and unsupported key types are skipped.
+	keys, err = ParseJWKS([]byte(`{"keys":[{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"},{"kty":"XYZ","kid":"xyz"}]}`))
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, err = ParseJWKS([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestJWKSRotation(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		oldKey, _  = rsa.GenerateKey(rand.Reader, 2048)
+		newKey, _  = rsa.GenerateKey(rand.Reader, 2048)
+		current    = marshalJWKS(t, map[string]any{"old": &oldKey.PublicKey})
+		fetchCount int
+		fail       bool
+		jwks       = NewJWKS(func(context.Context) ([]byte, error) {
+			fetchCount++
+			if fail {
+				return nil, errors.New("unavailable")
+			}
+			return current, nil
+		}, time.Hour, 0)
+	)
+
+	key, err := jwks.Key(ctx, "old")
+	assert.NoError(t, err)
+	assert.True(t, oldKey.PublicKey.Equal(key))
+	assert.Equal(t, 1, fetchCount)
+
+	// Cached keys are served without fetching.
+	_, err = jwks.Key(ctx, "old")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetchCount)
+
+	// An unknown key ID triggers a refresh.
+	current = marshalJWKS(t, map[string]any{"new": &newKey.PublicKey})
+	key, err = jwks.Key(ctx, "new")
+	assert.NoError(t, err)
+	assert.True(t, newKey.PublicKey.Equal(key))
+	assert.Equal(t, 2, fetchCount)
+
+	// A failing source keeps the previous keys.
+	fail = true
+	_, err = jwks.Key(ctx, "missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = jwks.Key(ctx, "new")
+	assert.NoError(t, err)
+}
+
+func TestJWKSCallerGone(t *testing.T) {
+	var (
+		key, _ = rsa.GenerateKey(rand.Reader, 2048)
+		jwks   = NewJWKS(func(ctx context.Context) ([]byte, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return marshalJWKS(t, map[string]any{"k1": &key.PublicKey}), nil
+		}, time.Hour, time.Hour)
+	)
+
+	// The fetch outlives a caller that went away, so that other requests don't wait for the next refresh.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := jwks.Key(ctx, "k1")
+	assert.NoError(t, err)
+
+	_, err = jwks.Key(context.Background(), "k1")
+	assert.NoError(t, err)
+}
+
+func TestFileJWKS(t *testing.T) {
+	var (
+		key, _ = rsa.GenerateKey(rand.Reader, 2048)
+		path   = filepath.Join(t.TempDir(), "jwks.json")
+	)
+	assert.NoError(t, os.WriteFile(path, marshalJWKS(t, map[string]any{"file": &key.PublicKey}), 0o600))
+
+	got, err := NewFileJWKS(path).Key(context.Background(), "file")
+	assert.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(got))
+}
@@ -0,0 +1,96 @@
+package jwt
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// InvalidTokenDetail is the problem detail of responses to requests with an invalid or missing token.
+const InvalidTokenDetail = "invalid or missing token"
+
+// Config is a struct that holds configuration options for the JWT middleware.
+type Config struct {
+	VerifierConfig
+	ExcludedPrefixes []string // Path prefixes that don't require a token.
+}
+
+// NewConfig creates a new Config struct with default values.
+// The KeySource must be set, for instance with NewRemoteJWKS.
+func NewConfig() *Config {
+	return &Config{
+		VerifierConfig: VerifierConfig{
+			KeySource:     nil,
+			Algorithms:    []string{"RS256", "ES256"},
+			Issuers:       []string{},
+			Audiences:     []string{},
+			ClockSkew:     time.Minute,
+			AllowNoExpiry: false,
+		},
+		ExcludedPrefixes: []string{},
+	}
+}
+
+// Middleware is the JWT bearer authentication middleware function that takes a Config struct and returns the middleware.
+// The verified claims are available with ClaimsFromContext and the caller with auth.IdentityFromContext.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	verifier := NewVerifier(config.VerifierConfig)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, err := BearerToken(r)
+			if err == nil {
+				var claims *Claims
+				if claims, err = verifier.Verify(r.Context(), token); err == nil {
+					r = r.WithContext(WithClaims(r.Context(), claims))
+					r = auth.WithIdentity(r, &auth.Identity{
+						Subject: claims.Subject,
+						Email:   claims.Email,
+						Method:  "jwt",
+						Roles:   claims.Roles(),
+						Scopes:  claims.Scopes(),
+					})
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			slog.Error("bearer token rejected", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+
+			// The error details stay in the logs, and an unavailable key source isn't the client's fault.
+			if errors.Is(err, ErrKeySource) {
+				problem.Error(w, http.StatusServiceUnavailable, ErrKeySource.Error())
+				return
+			}
+
+			// Requests without credentials get a bare challenge (RFC 6750, section 3.1).
+			if errors.Is(err, ErrMissingToken) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			} else {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			}
+			problem.Error(w, http.StatusUnauthorized, InvalidTokenDetail)
+		})
+	}
+}
+
+// BearerToken returns the bearer token of the request's Authorization header.
+func BearerToken(r *http.Request) (string, error) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrMissingToken
+	}
+	return strings.TrimSpace(token), nil
+}
@@ -0,0 +1,113 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+)
+
+func TestMiddleware(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	config := NewConfig()
+	config.KeySource = staticKeys{"k1": &key.PublicKey}
+	config.Audiences = []string{"api"}
+	config.ExcludedPrefixes = []string{"/health"}
+
+	var (
+		validToken   = sign(t, "RS256", "k1", key, map[string]any{"sub": "alice", "aud": "api", "roles": []string{"admin"}, "exp": time.Now().Add(time.Hour).Unix()})
+		expiredToken = sign(t, "RS256", "k1", key, map[string]any{"sub": "alice", "aud": "api", "exp": time.Now().Add(-time.Hour).Unix()})
+	)
+
+	tests := []struct {
+		name             string
+		path             string
+		authorization    string
+		wantStatus       int
+		wantAuthenticate string
+		wantSubject      string
+	}{
+		{"Valid token", "/api", "Bearer " + validToken, http.StatusOK, "", "alice"},
+		{"Lowercase scheme", "/api", "bearer " + validToken, http.StatusOK, "", "alice"},
+		{"Missing token", "/api", "", http.StatusUnauthorized, "Bearer", ""},
+		{"Wrong scheme", "/api", "Basic " + validToken, http.StatusUnauthorized, "Bearer", ""},
+		{"Expired token", "/api", "Bearer " + expiredToken, http.StatusUnauthorized, `Bearer error="invalid_token"`, ""},
+		{"Excluded path", "/health", "", http.StatusOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSubject string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+					gotSubject = identity.Subject
+					assert.Equal(t, []string{"admin"}, identity.Roles)
+				}
+				if claims, ok := ClaimsFromContext(r.Context()); ok {
+					assert.Equal(t, gotSubject, claims.Subject)
+				}
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantAuthenticate, rr.Header().Get("WWW-Authenticate"))
+			assert.Equal(t, tt.wantSubject, gotSubject)
+		})
+	}
+}
+
+// failingKeys is a KeySource whose backend is unavailable.
+type failingKeys struct{}
+
+func (failingKeys) Key(context.Context, string) (any, error) {
+	return nil, errors.New("fetch JWKS from https://keys.internal: unexpected status 500")
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	token := sign(t, "RS256", "k1", key, map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+
+	tests := []struct {
+		name       string
+		keySource  KeySource
+		wantStatus int
+		wantDetail string
+	}{
+		{"Unknown key", staticKeys{"k2": &key.PublicKey}, http.StatusUnauthorized, InvalidTokenDetail},
+		{"Key source unavailable", failingKeys{}, http.StatusServiceUnavailable, ErrKeySource.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.KeySource = tt.keySource
+
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(http.NotFoundHandler()).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.wantDetail)
+			assert.NotContains(t, rr.Body.String(), "keys.internal")
+			assert.NotContains(t, rr.Body.String(), "k1")
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // Register SHA-256 for crypto.Hash.
+	_ "crypto/sha512" // Register SHA-384 and SHA-512 for crypto.Hash.
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// Verification errors, wrapped with details by Verify.
+var (
+	ErrMalformed    = errors.New("malformed token")
+	ErrAlgorithm    = errors.New("algorithm not allowed")
+	ErrSignature    = errors.New("invalid signature")
+	ErrExpired      = errors.New("token expired")
+	ErrNoExpiry     = errors.New("token without expiry")
+	ErrNotYetValid  = errors.New("token not yet valid")
+	ErrIssuer       = errors.New("unexpected issuer")
+	ErrAudience     = errors.New("unexpected audience")
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrKeySource    = errors.New("signing keys unavailable")
+)
+
+// VerifierConfig is a struct that holds the rules tokens are verified against.
+type VerifierConfig struct {
+	KeySource     KeySource     // Source of signing keys.
+	Algorithms    []string      // Allowed signing algorithms.
+	Issuers       []string      // Accepted "iss" values, empty means any.
+	Audiences     []string      // Accepted "aud" values, empty means any.
+	ClockSkew     time.Duration // Tolerance applied to "exp", "nbf" and "iat".
+	AllowNoExpiry bool          // Flag to accept tokens without "exp", which never expire.
+}
+
+// Verifier verifies signed JWTs (RFC 7519).
+type Verifier struct {
+	config VerifierConfig
+	now    func() time.Time
+}
+
+// NewVerifier returns a new Verifier with the given rules.
+func NewVerifier(config VerifierConfig) *Verifier {
+	return &Verifier{config: config, now: time.Now}
+}
+
+// header is a JOSE header.
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Verify checks the token's signature and claims and returns the claims.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected 3 parts", ErrMalformed)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrMalformed, err)
+	}
+
+	// The algorithm must be pinned, never trusted from the token alone.
+	if h.Alg == "" || strings.EqualFold(h.Alg, "none") || !slices.Contains(v.config.Algorithms, h.Alg) {
+		return nil, fmt.Errorf("%w: %q", ErrAlgorithm, h.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrMalformed, err)
+	}
+
+	key, err := v.config.KeySource.Key(ctx, h.Kid)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrKeySource, err)
+	}
+
+	if err := verifySignature(h.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	claims, err := decodeClaims(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validate checks the registered claims.
+func (v *Verifier) validate(claims *Claims) error {
+	var (
+		now  = v.now()
+		skew = v.config.ClockSkew
+	)
+
+	if claims.ExpiresAt == nil && !v.config.AllowNoExpiry {
+		return ErrNoExpiry
+	}
+	if claims.ExpiresAt != nil && now.After(claims.ExpiresAt.Add(skew)) {
+		return fmt.Errorf("%w: at %s", ErrExpired, claims.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	if claims.NotBefore != nil && now.Add(skew).Before(claims.NotBefore.Time) {
+		return fmt.Errorf("%w: before %s", ErrNotYetValid, claims.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if claims.IssuedAt != nil && now.Add(skew).Before(claims.IssuedAt.Time) {
+		return fmt.Errorf("%w: issued in the future", ErrNotYetValid)
+	}
+	if len(v.config.Issuers) > 0 && !slices.Contains(v.config.Issuers, claims.Issuer) {
+		return fmt.Errorf("%w: %q", ErrIssuer, claims.Issuer)
+	}
+	if len(v.config.Audiences) > 0 && !claims.Audience.Contains(v.config.Audiences) {
+		return fmt.Errorf("%w: %v", ErrAudience, []string(claims.Audience))
+	}
+	return nil
+}
+
+// decodeSegment decodes a base64url-encoded JSON segment.
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// decodeClaims decodes the payload segment into Claims, keeping all claims in Raw.
+func decodeClaims(segment string) (*Claims, error) {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrMalformed, err)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformed, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&claims.Raw); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrMalformed, err)
+	}
+	return &claims, nil
+}
+
+// hashes maps algorithm suffixes to their hash functions.
+var hashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// curveBits maps ECDSA algorithms to the size of their curve.
+var curveBits = map[string]int{
+	"ES256": 256,
+	"ES384": 384,
+	"ES512": 521,
+}
+
+// verifySignature verifies the signature of the signing input with the key for the algorithm.
+func verifySignature(alg string, key any, signingInput, signature []byte) error {
+	if alg == "EdDSA" {
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, signingInput, signature) {
+			return ErrSignature
+		}
+		return nil
+	}
+
+	if len(alg) != 5 {
+		return fmt.Errorf("%w: %q", ErrAlgorithm, alg)
+	}
+	hash, ok := hashes[alg[2:]]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrAlgorithm, alg)
+	}
+
+	h := hash.New()
+	h.Write(signingInput)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(pub, hash, digest, signature) != nil {
+			return ErrSignature
+		}
+	case "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) != nil {
+			return ErrSignature
+		}
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Params().BitSize != curveBits[alg] {
+			return ErrSignature
+		}
+		size := (pub.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return ErrSignature
+		}
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return ErrSignature
+		}
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrSignature
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write(signingInput)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrSignature
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrAlgorithm, alg)
+	}
+	return nil
+}
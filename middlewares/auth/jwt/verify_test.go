@@ -0,0 +1,210 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// staticKeys is a KeySource serving fixed keys.
+type staticKeys map[string]any
+
+// Key implements KeySource.
+func (s staticKeys) Key(_ context.Context, kid string) (any, error) {
+	if key, ok := lookup(s, kid); ok {
+		return key, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// sign returns a token with the claims signed by the private key.
+func sign(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	assert.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	if alg == "EdDSA" {
+		signature = ed25519.Sign(key.(ed25519.PrivateKey), []byte(signingInput))
+	} else {
+		hash := hashes[alg[2:]]
+		h := hash.New()
+		h.Write([]byte(signingInput))
+		digest := h.Sum(nil)
+
+		switch alg[:2] {
+		case "RS":
+			signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), hash, digest)
+		case "PS":
+			signature, err = rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		case "ES":
+			priv := key.(*ecdsa.PrivateKey)
+			r, s, signErr := ecdsa.Sign(rand.Reader, priv, digest)
+			err = signErr
+			size := (priv.Params().BitSize + 7) / 8
+			signature = make([]byte, 2*size)
+			r.FillBytes(signature[:size])
+			s.FillBytes(signature[size:])
+		case "HS":
+			mac := hmac.New(hash.New, key.([]byte))
+			mac.Write([]byte(signingInput))
+			signature = mac.Sum(nil)
+		}
+		assert.NoError(t, err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyAlgorithms(t *testing.T) {
+	var (
+		rsaKey, _     = rsa.GenerateKey(rand.Reader, 2048)
+		ecKey, _      = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		ec384Key, _   = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		_, edKey, _   = ed25519.GenerateKey(rand.Reader)
+		secret        = []byte("0123456789abcdef0123456789abcdef")
+		claims        = map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+		allAlgorithms = []string{"RS256", "PS256", "ES256", "ES384", "EdDSA", "HS256"}
+		keys          = staticKeys{
+			"rsa":   &rsaKey.PublicKey,
+			"ec":    &ecKey.PublicKey,
+			"ec384": &ec384Key.PublicKey,
+			"ed":    edKey.Public(),
+			"hmac":  secret,
+		}
+	)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"RS256", sign(t, "RS256", "rsa", rsaKey, claims), nil},
+		{"PS256", sign(t, "PS256", "rsa", rsaKey, claims), nil},
+		{"ES256", sign(t, "ES256", "ec", ecKey, claims), nil},
+		{"ES384", sign(t, "ES384", "ec384", ec384Key, claims), nil},
+		{"EdDSA", sign(t, "EdDSA", "ed", edKey, claims), nil},
+		{"HS256", sign(t, "HS256", "hmac", secret, claims), nil},
+		{"Algorithm not allowed", sign(t, "RS512", "rsa", rsaKey, claims), ErrAlgorithm},
+		{"Curve mismatch", sign(t, "ES256", "ec384", ec384Key, claims), ErrSignature},
+		{"Key confusion", sign(t, "HS256", "rsa", secret, claims), ErrSignature},
+		{"Unknown key", sign(t, "RS256", "other", rsaKey, claims), ErrKeyNotFound},
+		{"Tampered signature", sign(t, "RS256", "rsa", rsaKey, claims) + "x", ErrSignature},
+		{"Not a JWT", "abc.def", ErrMalformed},
+	}
+
+	verifier := NewVerifier(VerifierConfig{KeySource: keys, Algorithms: allAlgorithms})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifier.Verify(context.Background(), tt.token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "alice", got.Subject)
+		})
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	var (
+		key, _ = rsa.GenerateKey(rand.Reader, 2048)
+		now    = time.Unix(1_700_000_000, 0)
+		config = VerifierConfig{
+			KeySource:  staticKeys{"": &key.PublicKey},
+			Algorithms: []string{"RS256"},
+			Issuers:    []string{"https://issuer.example"},
+			Audiences:  []string{"api"},
+			ClockSkew:  time.Minute,
+		}
+		valid = func(overrides map[string]any) map[string]any {
+			claims := map[string]any{
+				"iss": "https://issuer.example",
+				"aud": "api",
+				"sub": "alice",
+				"exp": now.Add(time.Hour).Unix(),
+				"nbf": now.Add(-time.Hour).Unix(),
+				"iat": now.Add(-time.Hour).Unix(),
+			}
+			for k, v := range overrides {
+				claims[k] = v
+			}
+			return claims
+		}
+	)
+
+	tests := []struct {
+		name          string
+		claims        map[string]any
+		allowNoExpiry bool
+		wantErr       error
+	}{
+		{"Valid", valid(nil), false, nil},
+		{"Audience array", valid(map[string]any{"aud": []string{"other", "api"}}), false, nil},
+		{"Expired within skew", valid(map[string]any{"exp": now.Add(-30 * time.Second).Unix()}), false, nil},
+		{"Expired", valid(map[string]any{"exp": now.Add(-2 * time.Minute).Unix()}), false, ErrExpired},
+		{"No expiry", valid(map[string]any{"exp": nil}), false, ErrNoExpiry},
+		{"No expiry allowed", valid(map[string]any{"exp": nil}), true, nil},
+		{"Not yet valid", valid(map[string]any{"nbf": now.Add(2 * time.Minute).Unix()}), false, ErrNotYetValid},
+		{"Issued in the future", valid(map[string]any{"iat": now.Add(2 * time.Minute).Unix()}), false, ErrNotYetValid},
+		{"Wrong issuer", valid(map[string]any{"iss": "https://evil.example"}), false, ErrIssuer},
+		{"Wrong audience", valid(map[string]any{"aud": "other"}), false, ErrAudience},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := config
+			config.AllowNoExpiry = tt.allowNoExpiry
+			verifier := NewVerifier(config)
+			verifier.now = func() time.Time { return now }
+
+			_, err := verifier.Verify(context.Background(), sign(t, "RS256", "", key, tt.claims))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestClaimsAccessors(t *testing.T) {
+	var (
+		key, _   = rsa.GenerateKey(rand.Reader, 2048)
+		verifier = NewVerifier(VerifierConfig{KeySource: staticKeys{"": &key.PublicKey}, Algorithms: []string{"RS256"}})
+		token    = sign(t, "RS256", "", key, map[string]any{
+			"sub":    "alice",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"scope":  "read write",
+			"roles":  []string{"admin"},
+			"tenant": "acme",
+		})
+	)
+
+	claims, err := verifier.Verify(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read", "write"}, claims.Scopes())
+	assert.Equal(t, []string{"admin"}, claims.Roles())
+
+	tenant, ok := claims.String("tenant")
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant)
+
+	_, ok = claims.String("roles")
+	assert.False(t, ok)
+}
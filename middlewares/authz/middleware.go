@@ -0,0 +1,160 @@
+package authz
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// Config is a struct that holds configuration options for the authorization middleware.
+type Config struct {
+	// Policies per route, keyed by path prefix, optionally preceded by a method and a space, such as "POST /orders".
+	// The longest matching prefix wins, and a route with a method wins over one without.
+	RoutePolicies map[string]Policy
+	DefaultPolicy *Policy      // Policy of unmatched routes, nil means they are allowed.
+	AuditLogger   *slog.Logger // Logger receiving every decision, nil means the default logger.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		RoutePolicies: map[string]Policy{},
+		DefaultPolicy: nil,
+		AuditLogger:   nil,
+	}
+}
+
+// policyFor returns the route and policy that apply to the request.
+func (c *Config) policyFor(r *http.Request) (string, *Policy) {
+	var (
+		matchedRoute  string
+		matchedPolicy *Policy
+		best          = -1
+	)
+
+	for route, policy := range c.RoutePolicies {
+		method, prefix := splitRoute(route)
+		if method != "" && method != r.Method || !strings.HasPrefix(r.URL.Path, prefix) {
+			continue
+		}
+
+		// Rank by prefix length first, then by method specificity.
+		rank := 2 * len(prefix)
+		if method != "" {
+			rank++
+		}
+		if rank > best {
+			policy := policy
+			matchedRoute, matchedPolicy, best = route, &policy, rank
+		}
+	}
+
+	if matchedPolicy == nil && c.DefaultPolicy != nil {
+		return "*", c.DefaultPolicy
+	}
+	return matchedRoute, matchedPolicy
+}
+
+// splitRoute splits a route key into its optional method and its path prefix.
+func splitRoute(route string) (string, string) {
+	if method, prefix, found := strings.Cut(route, " "); found {
+		return strings.ToUpper(method), strings.TrimSpace(prefix)
+	}
+	return "", route
+}
+
+// Middleware is the authorization middleware function that takes a Config struct and returns the middleware.
+// It must run inside an authentication middleware providing the auth.Identity.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	auditLogger := config.AuditLogger
+	if auditLogger == nil {
+		auditLogger = slog.Default().With(slog.Bool("audit", true))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, policy := config.policyFor(r)
+			if policy == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			decision, reason := policy.Evaluate(r)
+
+			var subject string
+			if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+				subject = identity.Subject
+			}
+
+			auditLogger.Info("authorization decision",
+				slog.String("decision", decision.String()),
+				slog.String("reason", reason),
+				slog.String("route", route),
+				slog.String("policy", policy.Name),
+				slog.String("subject", subject),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+			)
+
+			switch decision {
+			case Unauthenticated:
+				problem.Error(w, http.StatusUnauthorized, reason)
+			case Forbidden:
+				problem.Error(w, http.StatusForbidden, reason)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// Route is a route and its policy, as listed by Routes.
+type Route struct {
+	Route  string // Route key, see Config.RoutePolicies.
+	Policy Policy // Policy enforced on the route.
+}
+
+// Routes returns all routes with their policies, sorted by route, the default policy last as "*".
+func (c *Config) Routes() []Route {
+	routes := make([]Route, 0, len(c.RoutePolicies)+1)
+	for route, policy := range c.RoutePolicies {
+		routes = append(routes, Route{Route: route, Policy: policy})
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		_, pi := splitRoute(routes[i].Route)
+		_, pj := splitRoute(routes[j].Route)
+		if pi != pj {
+			return pi < pj
+		}
+		return routes[i].Route < routes[j].Route
+	})
+
+	if c.DefaultPolicy != nil {
+		routes = append(routes, Route{Route: "*", Policy: *c.DefaultPolicy})
+	}
+	return routes
+}
+
+// WriteRoutes writes the route-to-policy map as a table for security reviews.
+func (c *Config) WriteRoutes(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROUTE\tPOLICY\tREQUIREMENTS")
+
+	for _, route := range c.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", route.Route, route.Policy.Name, route.Policy.String())
+	}
+
+	if c.DefaultPolicy == nil {
+		fmt.Fprintln(tw, "*\t-\tallowed (no default policy)")
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,79 @@
+package authz
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+)
+
+func newTestConfig() *Config {
+	config := NewConfig()
+	config.RoutePolicies = map[string]Policy{
+		"/health":        {Name: "health", Public: true},
+		"/orders":        {Name: "orders-read", AllScopes: []string{"orders:read"}},
+		"POST /orders":   {Name: "orders-write", AllScopes: []string{"orders:write"}},
+		"/orders/export": {Name: "orders-export", AnyRoles: []string{"admin"}},
+	}
+	config.DefaultPolicy = &Policy{Name: "authenticated"}
+	return config
+}
+
+func TestMiddleware(t *testing.T) {
+	reader := &auth.Identity{Subject: "bob", Scopes: []string{"orders:read"}}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		identity   *auth.Identity
+		wantStatus int
+		wantPolicy string
+	}{
+		{"Public route", http.MethodGet, "/health", nil, http.StatusOK, "health"},
+		{"Prefix route allowed", http.MethodGet, "/orders/1", reader, http.StatusOK, "orders-read"},
+		{"Method route wins", http.MethodPost, "/orders", reader, http.StatusForbidden, "orders-write"},
+		{"Longest prefix wins", http.MethodGet, "/orders/export", reader, http.StatusForbidden, "orders-export"},
+		{"Default policy unauthenticated", http.MethodGet, "/profile", nil, http.StatusUnauthorized, "authenticated"},
+		{"Default policy allowed", http.MethodGet, "/profile", reader, http.StatusOK, "authenticated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			config := newTestConfig()
+			config.AuditLogger = slog.New(slog.NewJSONHandler(buf, nil))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.identity != nil {
+				req = auth.WithIdentity(req, tt.identity)
+			}
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Contains(t, buf.String(), `"policy":"`+tt.wantPolicy+`"`)
+		})
+	}
+}
+
+func TestWriteRoutes(t *testing.T) {
+	buf := new(bytes.Buffer)
+	assert.NoError(t, newTestConfig().WriteRoutes(buf))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 6)
+	assert.True(t, strings.HasPrefix(lines[0], "ROUTE"))
+	assert.True(t, strings.HasPrefix(lines[1], "/health"))
+	assert.True(t, strings.HasPrefix(lines[2], "/orders "))
+	assert.True(t, strings.HasPrefix(lines[3], "POST /orders"))
+	assert.Contains(t, lines[3], "all scopes of [orders:write]")
+	assert.True(t, strings.HasPrefix(lines[5], "*"))
+}
@@ -0,0 +1,112 @@
+package authz
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+)
+
+// Policy is an authorization requirement.
+type Policy struct {
+	Name          string                                   // Name of the policy, reported in audit logs.
+	Public        bool                                     // Allow anonymous requests, skipping all other checks.
+	AnyRoles      []string                                 // The caller must have at least one of these roles, if any.
+	AllScopes     []string                                 // The caller must have all of these scopes.
+	Predicate     func(*http.Request, *auth.Identity) bool // Custom check, nil means no check.
+	PredicateName string                                   // Description of the predicate for policy dumps.
+}
+
+// Decision is the outcome of evaluating a Policy.
+type Decision int
+
+const (
+	// Allow grants access.
+	Allow Decision = iota
+	// Unauthenticated denies access because the request has no identity.
+	Unauthenticated
+	// Forbidden denies access because the identity doesn't satisfy the policy.
+	Forbidden
+)
+
+// String implements fmt.Stringer.
+func (d Decision) String() string {
+	switch d {
+	case Allow:
+		return "allow"
+	case Unauthenticated:
+		return "unauthenticated"
+	case Forbidden:
+		return "forbidden"
+	default:
+		return "unknown"
+	}
+}
+
+// Evaluate evaluates the policy for the request and returns the decision with its reason.
+func (p *Policy) Evaluate(r *http.Request) (Decision, string) {
+	if p.Public {
+		return Allow, "public"
+	}
+
+	identity, ok := auth.IdentityFromContext(r.Context())
+	if !ok {
+		return Unauthenticated, "no authenticated identity"
+	}
+
+	if len(p.AnyRoles) > 0 && !containsAny(identity.Roles, p.AnyRoles) {
+		return Forbidden, "missing one of roles " + strings.Join(p.AnyRoles, ", ")
+	}
+
+	for _, scope := range p.AllScopes {
+		if !slices.Contains(identity.Scopes, scope) {
+			return Forbidden, "missing scope " + scope
+		}
+	}
+
+	if p.Predicate != nil && !p.Predicate(r, identity) {
+		return Forbidden, "predicate " + p.predicateName() + " not satisfied"
+	}
+
+	return Allow, "policy satisfied"
+}
+
+// String describes the policy's requirements.
+func (p *Policy) String() string {
+	if p.Public {
+		return "public"
+	}
+
+	requirements := []string{"authenticated"}
+	if len(p.AnyRoles) > 0 {
+		requirements = append(requirements, fmt.Sprintf("any role of [%s]", strings.Join(p.AnyRoles, ", ")))
+	}
+	if len(p.AllScopes) > 0 {
+		requirements = append(requirements, fmt.Sprintf("all scopes of [%s]", strings.Join(p.AllScopes, ", ")))
+	}
+	if p.Predicate != nil {
+		requirements = append(requirements, "predicate "+p.predicateName())
+	}
+	return strings.Join(requirements, ", ")
+}
+
+// predicateName returns the description of the predicate.
+func (p *Policy) predicateName() string {
+	if p.PredicateName != "" {
+		return p.PredicateName
+	}
+	return "custom"
+}
+
+// containsAny checks if any of the values exists in the list.
+func containsAny(list, values []string) bool {
+	for _, value := range values {
+		if slices.Contains(list, value) {
+			return true
+		}
+	}
+	return false
+}
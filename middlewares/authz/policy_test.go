@@ -0,0 +1,69 @@
+package authz
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+)
+
+func TestEvaluate(t *testing.T) {
+	var (
+		admin  = &auth.Identity{Subject: "alice", Roles: []string{"admin"}, Scopes: []string{"orders:read", "orders:write"}}
+		reader = &auth.Identity{Subject: "bob", Roles: []string{"viewer"}, Scopes: []string{"orders:read"}}
+		owner  = func(r *http.Request, identity *auth.Identity) bool {
+			return r.URL.Query().Get("owner") == identity.Subject
+		}
+	)
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		identity *auth.Identity
+		target   string
+		want     Decision
+	}{
+		{"Public without identity", Policy{Public: true}, nil, "/", Allow},
+		{"Authenticated without identity", Policy{}, nil, "/", Unauthenticated},
+		{"Authenticated with identity", Policy{}, reader, "/", Allow},
+		{"Any role satisfied", Policy{AnyRoles: []string{"admin", "editor"}}, admin, "/", Allow},
+		{"Any role missing", Policy{AnyRoles: []string{"admin", "editor"}}, reader, "/", Forbidden},
+		{"All scopes satisfied", Policy{AllScopes: []string{"orders:read", "orders:write"}}, admin, "/", Allow},
+		{"One scope missing", Policy{AllScopes: []string{"orders:read", "orders:write"}}, reader, "/", Forbidden},
+		{"Predicate satisfied", Policy{Predicate: owner}, reader, "/?owner=bob", Allow},
+		{"Predicate not satisfied", Policy{Predicate: owner}, reader, "/?owner=alice", Forbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.identity != nil {
+				req = auth.WithIdentity(req, tt.identity)
+			}
+
+			got, _ := tt.policy.Evaluate(req)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPolicyString(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   string
+	}{
+		{"Public", Policy{Public: true}, "public"},
+		{"Authenticated", Policy{}, "authenticated"},
+		{"Full", Policy{AnyRoles: []string{"admin"}, AllScopes: []string{"a", "b"}, Predicate: func(*http.Request, *auth.Identity) bool { return true }, PredicateName: "owner"}, "authenticated, any role of [admin], all scopes of [a, b], predicate owner"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.String())
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package bodylimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// budget is a weighted semaphore bounding the number of in-flight request body bytes.
+type budget struct {
+	mu      sync.Mutex
+	size    int64
+	used    int64
+	waiters list.List
+}
+
+type waiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// newBudget returns a new budget of the given size in bytes.
+func newBudget(size int64) *budget {
+	return &budget{size: size}
+}
+
+// tryAcquire reserves n bytes without waiting and reports whether it succeeded.
+func (b *budget) tryAcquire(n int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.size-b.used >= n && b.waiters.Len() == 0 {
+		b.used += n
+		return true
+	}
+	return false
+}
+
+// acquire reserves n bytes, waiting in FIFO order until they are available or the context is done.
+func (b *budget) acquire(ctx context.Context, n int64) bool {
+	b.mu.Lock()
+	if b.size-b.used >= n && b.waiters.Len() == 0 {
+		b.used += n
+		b.mu.Unlock()
+		return true
+	}
+
+	// A reservation larger than the whole budget can never be satisfied.
+	if n > b.size {
+		b.mu.Unlock()
+		return false
+	}
+
+	w := waiter{n: n, ready: make(chan struct{})}
+	elem := b.waiters.PushBack(w)
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return true
+	case <-ctx.Done():
+		b.mu.Lock()
+		select {
+		case <-w.ready:
+			// Acquired after the context was done, give the bytes back.
+			b.used -= n
+			b.notifyWaiters()
+		default:
+			isFront := b.waiters.Front() == elem
+			b.waiters.Remove(elem)
+			// Removing the front waiter may unblock the ones queued behind it.
+			if isFront && b.size > b.used {
+				b.notifyWaiters()
+			}
+		}
+		b.mu.Unlock()
+		return false
+	}
+}
+
+// release returns n bytes to the budget.
+func (b *budget) release(n int64) {
+	b.mu.Lock()
+	b.used -= n
+	b.notifyWaiters()
+	b.mu.Unlock()
+}
+
+// notifyWaiters wakes up queued waiters in order while there is room for them.
+func (b *budget) notifyWaiters() {
+	for {
+		next := b.waiters.Front()
+		if next == nil {
+			return
+		}
+
+		w := next.Value.(waiter)
+		if b.size-b.used < w.n {
+			return
+		}
+
+		b.used += w.n
+		b.waiters.Remove(next)
+		close(w.ready)
+	}
+}
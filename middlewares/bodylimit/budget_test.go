@@ -0,0 +1,34 @@
+package bodylimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	b := newBudget(100)
+
+	assert.True(t, b.tryAcquire(60))
+	assert.False(t, b.tryAcquire(60))
+
+	// A waiter is woken up once enough bytes are released.
+	acquired := make(chan bool)
+	go func() {
+		acquired <- b.acquire(context.Background(), 60)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	b.release(60)
+	assert.True(t, <-acquired)
+
+	// A waiter gives up when its context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, b.acquire(ctx, 60))
+
+	// A reservation larger than the budget never succeeds.
+	assert.False(t, b.acquire(context.Background(), 200))
+}
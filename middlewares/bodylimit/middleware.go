@@ -0,0 +1,283 @@
+package bodylimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// Config is a struct that holds configuration options for the body limit middleware.
+type Config struct {
+	DefaultLimit int64            // Maximum request body size in bytes, 0 means unlimited.
+	RouteLimits  map[string]int64 // Maximum request body size in bytes per path prefix.
+	Budget       int64            // Maximum number of in-flight request body bytes across all requests, 0 means unlimited.
+	QueueTimeout time.Duration    // Maximum time to wait for budget before rejecting, 0 means reject immediately.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		DefaultLimit: 1 << 20, // 1 MiB.
+		RouteLimits:  map[string]int64{},
+		Budget:       0,
+		QueueTimeout: 0,
+	}
+}
+
+// limitFor returns the body size limit that applies to the given path.
+func (c *Config) limitFor(path string) int64 {
+	if _, limit, ok := common.MatchPrefix(path, c.RouteLimits); ok {
+		return limit
+	}
+	return c.DefaultLimit
+}
+
+// Middleware is the body limit middleware function that takes a Config struct and returns the middleware.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	var inFlight *budget
+	if config.Budget > 0 {
+		inFlight = newBudget(config.Budget)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Requests without a body have nothing to limit.
+			if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit := config.limitFor(r.URL.Path)
+
+			// Reject early when the declared length is already over the limit.
+			if limit > 0 && r.ContentLength > limit {
+				slog.Error("request body too large",
+					slog.String("path", r.URL.Path),
+					slog.Int64("contentLength", r.ContentLength),
+					slog.Int64("limit", limit),
+				)
+				problem.Error(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", limit))
+				return
+			}
+
+			if inFlight != nil {
+				// Reserve the declared length, or the worst case when the length is unknown.
+				reserved := r.ContentLength
+				if reserved < 0 && limit > 0 {
+					reserved = limit
+				}
+
+				// Without a limit, the worst case is unknown, so bytes are counted against the budget as they're read.
+				if reserved < 0 {
+					serveBudgeted(next, w, r, inFlight, config.QueueTimeout)
+					return
+				}
+
+				if reserved > 0 {
+					if !acquire(r.Context(), inFlight, reserved, config.QueueTimeout) {
+						slog.Error("request body budget exhausted",
+							slog.String("path", r.URL.Path),
+							slog.Int64("reserved", reserved),
+						)
+						rejectOverBudget(w)
+						return
+					}
+					defer inFlight.release(reserved)
+				}
+			}
+
+			ServeLimited(next, w, r, limit)
+		})
+	}
+}
+
+// ServeLimited serves the request with its body limited to limit bytes, 0 meaning unlimited.
+// The response is replaced with a 413 problem when the handler reads past the limit.
+func ServeLimited(next http.Handler, w http.ResponseWriter, r *http.Request, limit int64) {
+	if limit <= 0 {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit)}
+	r.Body = body
+
+	lw := &limitWriter{ResponseWriter: w, body: body, limit: limit}
+	next.ServeHTTP(lw, r)
+
+	// The handler gave up on the body without writing a response.
+	if body.exceeded && !lw.wroteHeader {
+		lw.WriteHeader(http.StatusRequestEntityTooLarge)
+	}
+}
+
+// serveBudgeted serves the request with the bytes read from its body reserved from the budget until it's served.
+// The response is replaced with a 503 problem when the handler reads past the remaining budget.
+func serveBudgeted(next http.Handler, w http.ResponseWriter, r *http.Request, b *budget, timeout time.Duration) {
+	body := &budgetedBody{ReadCloser: r.Body, ctx: r.Context(), budget: b, timeout: timeout}
+	r.Body = body
+	defer func() { b.release(body.reserved) }()
+
+	bw := &budgetWriter{ResponseWriter: w, body: body}
+	next.ServeHTTP(bw, r)
+
+	// The handler gave up on the body without writing a response.
+	if body.exhausted && !bw.wroteHeader {
+		bw.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// rejectOverBudget writes the 503 problem of requests over the in-flight budget.
+func rejectOverBudget(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	problem.Error(w, http.StatusServiceUnavailable, errBudgetExhausted.Error())
+}
+
+// acquire reserves n bytes from the budget, waiting up to timeout.
+func acquire(ctx context.Context, b *budget, n int64, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return b.tryAcquire(n)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return b.acquire(ctx, n)
+}
+
+// limitedBody records whether the handler tried to read past the body limit.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+// Read implements io.Reader.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.exceeded = true
+	}
+
+	return n, err
+}
+
+// errBudgetExhausted is returned when reading a body past the remaining in-flight budget.
+var errBudgetExhausted = errors.New("too many in-flight request bytes")
+
+// budgetedBody reserves the bytes read from the body from the budget.
+type budgetedBody struct {
+	io.ReadCloser
+	ctx       context.Context
+	budget    *budget
+	timeout   time.Duration
+	reserved  int64
+	exhausted bool
+}
+
+// Read implements io.Reader.
+func (b *budgetedBody) Read(p []byte) (int, error) {
+	if b.exhausted {
+		return 0, errBudgetExhausted
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if !acquire(b.ctx, b.budget, int64(n), b.timeout) {
+			b.exhausted = true
+			slog.Error("request body budget exhausted", slog.Int64("reserved", b.reserved))
+			return 0, errBudgetExhausted
+		}
+		b.reserved += int64(n)
+	}
+	return n, err
+}
+
+// budgetWriter replaces the handler's response with a 503 problem once the budget was exhausted.
+type budgetWriter struct {
+	http.ResponseWriter
+	body        *budgetedBody
+	wroteHeader bool
+	overridden  bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *budgetWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if w.body.exhausted {
+		w.overridden = true
+		rejectOverBudget(w.ResponseWriter)
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter and discards the handler's body when the response was overridden.
+func (w *budgetWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.overridden {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *budgetWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// limitWriter replaces the handler's response with a 413 problem once the body limit was exceeded.
+type limitWriter struct {
+	http.ResponseWriter
+	body        *limitedBody
+	limit       int64
+	wroteHeader bool
+	overridden  bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *limitWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if w.body.exceeded {
+		w.overridden = true
+		problem.Error(w.ResponseWriter, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", w.limit))
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter and discards the handler's body when the response was overridden.
+func (w *limitWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.overridden {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *limitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
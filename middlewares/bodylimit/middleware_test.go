@@ -0,0 +1,126 @@
+package bodylimit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	config := NewConfig()
+	config.DefaultLimit = 10
+	config.RouteLimits = map[string]int64{"/upload": 100}
+
+	readAll := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name          string
+		path          string
+		body          string
+		contentLength int64
+		wantStatus    int
+	}{
+		{"Within default limit", "/api", "small", 5, http.StatusOK},
+		{"Declared length over default limit", "/api", strings.Repeat("a", 20), 20, http.StatusRequestEntityTooLarge},
+		{"Unknown length over default limit", "/api", strings.Repeat("a", 20), -1, http.StatusRequestEntityTooLarge},
+		{"Within route limit", "/upload/file", strings.Repeat("a", 50), 50, http.StatusOK},
+		{"Over route limit", "/upload/file", strings.Repeat("a", 150), -1, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.ContentLength = tt.contentLength
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(readAll).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusRequestEntityTooLarge {
+				assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestMiddlewareBudget(t *testing.T) {
+	config := NewConfig()
+	config.DefaultLimit = 100
+	config.Budget = 150
+
+	var (
+		entered = make(chan struct{})
+		unblock = make(chan struct{})
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entered <- struct{}{}
+			<-unblock
+		})
+		middleware = Middleware(config)(handler)
+	)
+
+	// Hold 100 bytes of budget with a first request.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 100)))
+		middleware.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-entered
+
+	// A second request that does not fit in the remaining budget is rejected.
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 100)))
+	rr := httptest.NewRecorder()
+	middleware.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+
+	close(unblock)
+	<-done
+}
+
+func TestMiddlewareBudgetUnknownLength(t *testing.T) {
+	config := NewConfig()
+	config.DefaultLimit = 0
+	config.Budget = 150
+
+	handler := Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+
+	tests := []struct {
+		name       string
+		size       int
+		wantStatus int
+	}{
+		{"Within budget", 100, http.StatusOK},
+		{"Over budget", 200, http.StatusServiceUnavailable},
+		{"Budget released", 150, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Chunked bodies have an unknown length.
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", tt.size)))
+			req.ContentLength = -1
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
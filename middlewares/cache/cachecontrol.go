@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// directives are parsed Cache-Control directives, mapping lowercase names to their unquoted values.
+type directives map[string]string
+
+// parseCacheControl parses Cache-Control header values.
+func parseCacheControl(values []string) directives {
+	d := directives{}
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			d[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+	return d
+}
+
+// has checks if the directive is present.
+func (d directives) has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// duration returns the delta-seconds argument of the directive.
+func (d directives) duration(name string) (time.Duration, bool) {
+	arg, ok := d[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// freshness returns how long a response may be served by a shared cache, and for how long after that it may be
+// served stale while revalidating. A zero lifetime means the response must not be served from the cache.
+func (d directives) freshness() (time.Duration, time.Duration) {
+	if d.has("no-store") || d.has("no-cache") || d.has("private") {
+		return 0, 0
+	}
+
+	lifetime, ok := d.duration("s-maxage")
+	if !ok {
+		lifetime, _ = d.duration("max-age")
+	}
+	staleWhileRevalidate, _ := d.duration("stale-while-revalidate")
+	return lifetime, staleWhileRevalidate
+}
@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreshness(t *testing.T) {
+	tests := []struct {
+		name                     string
+		values                   []string
+		wantLifetime             time.Duration
+		wantStaleWhileRevalidate time.Duration
+	}{
+		{"Max age", []string{"max-age=60"}, time.Minute, 0},
+		{"Shared max age wins", []string{"max-age=60, s-maxage=120"}, 2 * time.Minute, 0},
+		{"Stale while revalidate", []string{"public", `max-age=60, stale-while-revalidate="30"`}, time.Minute, 30 * time.Second},
+		{"Private", []string{"private, max-age=60"}, 0, 0},
+		{"No store", []string{"no-store, max-age=60"}, 0, 0},
+		{"No cache", []string{"No-Cache, max-age=60"}, 0, 0},
+		{"Invalid max age", []string{"max-age=-1"}, 0, 0},
+		{"Missing", nil, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lifetime, staleWhileRevalidate := parseCacheControl(tt.values).freshness()
+			assert.Equal(t, tt.wantLifetime, lifetime)
+			assert.Equal(t, tt.wantStaleWhileRevalidate, staleWhileRevalidate)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// statusKey is the attribute key for the cache lookup status.
+const statusKey = attribute.Key("cache.status")
+
+type Metrics struct {
+	lookupCounter metric.Int64Counter
+}
+
+// NewMetrics returns a new Metrics instance.
+func NewMetrics(meter *metric.Meter) *Metrics {
+	lookupCounter, _ := (*meter).Int64Counter(
+		"http_cache_lookups_total",
+		metric.WithDescription("Total number of HTTP response cache lookups."),
+	)
+
+	return &Metrics{
+		lookupCounter: lookupCounter,
+	}
+}
+
+// IncreaseLookupCounter increases the cache lookup counter by 1.
+func (m *Metrics) IncreaseLookupCounter(ctx context.Context, status string) {
+	m.lookupCounter.Add(ctx, 1, metric.WithAttributes(statusKey.String(status)))
+}
@@ -0,0 +1,333 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/secure"
+)
+
+// Lookup statuses, used as metric attribute values.
+const (
+	statusHit    = "hit"
+	statusStale  = "stale"
+	statusMiss   = "miss"
+	statusBypass = "bypass"
+)
+
+// Config is a struct that holds configuration options for the cache middleware.
+type Config struct {
+	Store             Store                      // Storage backend, nil means a LRUStore of MaxBytes.
+	MaxBytes          int64                      // Maximum size of the default LRUStore.
+	MaxBodySize       int                        // Maximum size of buffered response bodies, larger ones are streamed.
+	KeyFunc           func(*http.Request) string // Function returning the cache key of a request.
+	Name              string                     // Cache name reported in the Cache-Status header.
+	CredentialHeaders []string                   // Request headers carrying credentials, whose responses must be marked as shareable.
+	ExcludedPrefixes  []string                   // Path prefixes that are never cached.
+}
+
+// NewConfig creates a new Config struct with default values.
+// The session cookie, API key and Identity-Aware Proxy assertion authenticate requests like Authorization.
+func NewConfig() *Config {
+	return &Config{
+		Store:             nil,
+		MaxBytes:          64 << 20,
+		MaxBodySize:       1 << 20,
+		KeyFunc:           KeyByURL,
+		Name:              "go-http",
+		CredentialHeaders: append([]string{}, common.CredentialHeaders...),
+		ExcludedPrefixes:  []string{},
+	}
+}
+
+// KeyByURL keys requests on their scheme, host and request URI.
+func KeyByURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if r.URL.Scheme != "" {
+		scheme = r.URL.Scheme
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// Middleware is the cache middleware function that takes a Config struct and returns the middleware.
+// GET and HEAD responses get an ETag computed from their body when missing, and conditional requests are
+// answered with 304. Responses are stored according to their Cache-Control s-maxage or max-age directives,
+// per variant of their Vary header, and served stale while revalidating in the background when allowed.
+// Responses to requests whose CSP nonce was read with secure.Nonce are never stored.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	store := config.Store
+	if store == nil {
+		store = NewLRUStore(config.MaxBytes)
+	}
+
+	var (
+		pkgName      = reflect.TypeOf(struct{}{}).PkgPath()
+		meter        = otel.GetMeterProvider().Meter(pkgName)
+		metrics      = NewMetrics(&meter)
+		revalidating sync.Map
+	)
+
+	return func(next http.Handler) http.Handler {
+		// fetch serves the request with the handler, storing the response if cacheable.
+		// It returns nil when the response was streamed to w instead.
+		fetch := func(w http.ResponseWriter, r *http.Request, key string, storable bool) *Entry {
+			rec := newRecorder(w, config.MaxBodySize)
+			next.ServeHTTP(rec, r)
+			if rec.passthrough {
+				return nil
+			}
+
+			entry := newEntry(rec, time.Now(), r.Method == http.MethodGet)
+			if storable && r.Method == http.MethodGet && cacheable(r, entry, config.CredentialHeaders) {
+				if err := storeEntry(r.Context(), store, key, r, entry); err != nil {
+					slog.Error("failed to store cache entry", slog.String("error", err.Error()))
+				}
+			}
+			return entry
+		}
+
+		revalidate := func(r *http.Request, key string) {
+			if _, loaded := revalidating.LoadOrStore(key, struct{}{}); loaded {
+				return
+			}
+
+			req := r.Clone(common.Detach(r.Context()))
+			req.Method = http.MethodGet
+			req.Header.Del("If-None-Match")
+			req.Header.Del("If-Modified-Since")
+
+			go func() {
+				defer revalidating.Delete(key)
+				fetch(&discardWriter{header: http.Header{}}, req, key, true)
+			}()
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) || common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var (
+				key       = config.KeyFunc(r)
+				requestCC = parseCacheControl(r.Header.Values("Cache-Control"))
+				storable  = !requestCC.has("no-store")
+				now       = time.Now()
+			)
+
+			if storable && !requestCC.has("no-cache") {
+				if entry := lookup(r.Context(), store, key, r); entry != nil {
+					switch {
+					case entry.fresh(now):
+						metrics.IncreaseLookupCounter(r.Context(), statusHit)
+						serve(w, r, entry, config.Name+"; hit", now)
+						return
+					case entry.usableStale(now):
+						metrics.IncreaseLookupCounter(r.Context(), statusStale)
+						revalidate(r, key)
+						serve(w, r, entry, config.Name+"; hit; fwd=stale", now)
+						return
+					}
+				}
+				metrics.IncreaseLookupCounter(r.Context(), statusMiss)
+			} else {
+				metrics.IncreaseLookupCounter(r.Context(), statusBypass)
+			}
+
+			if entry := fetch(w, r, key, storable); entry != nil {
+				cacheStatus := config.Name + "; fwd=miss"
+				if !storable || requestCC.has("no-cache") {
+					cacheStatus = config.Name + "; fwd=request"
+				}
+				serve(w, r, entry, cacheStatus, time.Time{})
+			}
+		})
+	}
+}
+
+// newEntry returns the entry of a buffered response, with an ETag computed from its body when missing
+// and the body is complete, which isn't the case for HEAD requests. Only the headers set by the handler
+// are kept, as those set by outer middlewares, such as the request ID, belong to the current request.
+func newEntry(rec *recorder, now time.Time, completeBody bool) *Entry {
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	header := common.AddedHeaders(rec.initialHeader, rec.Header())
+	body := append([]byte(nil), rec.body.Bytes()...)
+	if status == http.StatusOK && completeBody && header.Get("ETag") == "" {
+		sum := sha256.Sum256(body)
+		header.Set("ETag", `"`+base64.RawURLEncoding.EncodeToString(sum[:16])+`"`)
+	}
+
+	lifetime, staleWhileRevalidate := parseCacheControl(header.Values("Cache-Control")).freshness()
+	return &Entry{
+		Status:               status,
+		Header:               header,
+		Body:                 body,
+		Vary:                 varyHeaders(header),
+		StoredAt:             now,
+		Lifetime:             lifetime,
+		StaleWhileRevalidate: staleWhileRevalidate,
+	}
+}
+
+// cacheable checks if the response to the request may be stored by a shared cache.
+func cacheable(r *http.Request, entry *Entry, credentialHeaders []string) bool {
+	if entry.Status != http.StatusOK || entry.Lifetime <= 0 || entry.Header.Get("Set-Cookie") != "" {
+		return false
+	}
+	for _, name := range entry.Vary {
+		if name == "*" {
+			return false
+		}
+	}
+
+	// A replayed response would embed the CSP nonce of another request, which the new policy doesn't allow.
+	if secure.NonceRead(r.Context()) {
+		return false
+	}
+
+	// Responses to authenticated requests must be explicitly marked as shareable.
+	for _, name := range credentialHeaders {
+		if r.Header.Get(name) != "" {
+			cc := parseCacheControl(entry.Header.Values("Cache-Control"))
+			return cc.has("public") || cc.has("s-maxage")
+		}
+	}
+	return true
+}
+
+// lookup returns the stored entry matching the request, selecting its variant when the response varies.
+func lookup(ctx context.Context, store Store, key string, r *http.Request) *Entry {
+	entry, err := store.Get(ctx, key)
+	if err != nil {
+		return nil
+	}
+	if len(entry.Vary) == 0 {
+		return entry
+	}
+
+	variant, err := store.Get(ctx, variantKey(key, entry.Vary, r))
+	if err != nil {
+		return nil
+	}
+	return variant
+}
+
+// storeEntry stores the entry, under a secondary key for its variant when the response varies.
+func storeEntry(ctx context.Context, store Store, key string, r *http.Request, entry *Entry) error {
+	if len(entry.Vary) == 0 {
+		return store.Set(ctx, key, entry)
+	}
+
+	marker := &Entry{
+		Vary:                 entry.Vary,
+		StoredAt:             entry.StoredAt,
+		Lifetime:             entry.Lifetime,
+		StaleWhileRevalidate: entry.StaleWhileRevalidate,
+	}
+	if err := store.Set(ctx, key, marker); err != nil {
+		return err
+	}
+	return store.Set(ctx, variantKey(key, entry.Vary, r), entry)
+}
+
+// variantKey returns the secondary key of the variant selected by the request headers.
+func variantKey(key string, vary []string, r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, name := range vary {
+		b.WriteString("\x00")
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// varyHeaders returns the canonical names of the request headers listed by the Vary header.
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// serve writes the entry, or 304 Not Modified when the request preconditions match it.
+// A non-zero now adds the Age header, for entries served from the store.
+func serve(w http.ResponseWriter, r *http.Request, entry *Entry, cacheStatus string, now time.Time) {
+	header := w.Header()
+	common.MergeHeaders(header, entry.Header)
+	header.Set("Cache-Status", cacheStatus)
+	if !now.IsZero() {
+		header.Set("Age", strconv.FormatInt(int64(entry.age(now)/time.Second), 10))
+	}
+
+	if entry.Status == http.StatusOK && notModified(r, entry.Header) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if r.Method != http.MethodHead && header.Get("Content-Length") == "" {
+		header.Set("Content-Length", strconv.Itoa(len(entry.Body)))
+	}
+	w.WriteHeader(entry.Status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(entry.Body)
+	}
+}
+
+// notModified evaluates the If-None-Match and If-Modified-Since preconditions as defined by RFC 9110.
+func notModified(r *http.Request, header http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, header.Get("ETag"))
+	}
+
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ims)
+}
+
+// etagMatch checks if the entity tag matches one of the If-None-Match list, with a weak comparison.
+func etagMatch(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2n3g5c9/go-http/middlewares/requestid"
+	"github.com/2n3g5c9/go-http/middlewares/secure"
+)
+
+// countingHandler returns a handler counting its calls, responding with the call number.
+func countingHandler(calls *atomic.Int32, cacheControl string, header http.Header) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+		_, _ = w.Write([]byte("response " + strconv.Itoa(int(n))))
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		header       http.Header
+		method       string
+		requestCC    string
+		wantCalls    int32
+		wantBody     string
+		wantStatus   string
+	}{
+		{"Cacheable", "max-age=60", nil, http.MethodGet, "", 1, "response 1", "go-http; hit"},
+		{"Not cacheable", "no-store", nil, http.MethodGet, "", 2, "response 2", "go-http; fwd=miss"},
+		{"Private", "private, max-age=60", nil, http.MethodGet, "", 2, "response 2", "go-http; fwd=miss"},
+		{"Set-Cookie", "max-age=60", http.Header{"Set-Cookie": {"a=b"}}, http.MethodGet, "", 2, "response 2", "go-http; fwd=miss"},
+		{"Vary star", "max-age=60", http.Header{"Vary": {"*"}}, http.MethodGet, "", 2, "response 2", "go-http; fwd=miss"},
+		{"Request no-cache", "max-age=60", nil, http.MethodGet, "no-cache", 2, "response 2", "go-http; fwd=request"},
+		{"Unsafe method", "max-age=60", nil, http.MethodPost, "", 2, "response 2", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			handler := Middleware(NewConfig())(countingHandler(&calls, tt.cacheControl, tt.header))
+
+			var rr *httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(tt.method, "/items?page=1", nil)
+				if tt.requestCC != "" {
+					req.Header.Set("Cache-Control", tt.requestCC)
+				}
+				rr = httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+			}
+
+			assert.Equal(t, tt.wantCalls, calls.Load())
+			assert.Equal(t, tt.wantBody, rr.Body.String())
+			assert.Equal(t, tt.wantStatus, rr.Header().Get("Cache-Status"))
+		})
+	}
+}
+
+func TestMiddlewareCredentials(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		value        string
+		cacheControl string
+		wantCalls    int32
+	}{
+		{"Authorization", "Authorization", "Bearer token", "max-age=60", 2},
+		{"Session cookie", "Cookie", "__Host-session=abc", "max-age=60", 2},
+		{"API key", "X-API-Key", "key", "max-age=60", 2},
+		{"IAP assertion", "X-Goog-IAP-JWT-Assertion", "assertion", "max-age=60", 2},
+		{"Public", "Cookie", "__Host-session=abc", "public, max-age=60", 1},
+		{"Shared max age", "X-API-Key", "key", "s-maxage=60", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			handler := Middleware(NewConfig())(countingHandler(&calls, tt.cacheControl, nil))
+
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/me", nil)
+				req.Header.Set(tt.header, tt.value)
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestMiddlewareOuterHeaders(t *testing.T) {
+	var calls atomic.Int32
+	handler := requestid.Middleware(requestid.NewConfig())(
+		Middleware(NewConfig())(countingHandler(&calls, "max-age=60", nil)),
+	)
+
+	ids := make([]string, 2)
+	for i := range ids {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.Equal(t, "response 1", rr.Body.String())
+		ids[i] = rr.Header().Get(requestid.Header)
+		require.NotEmpty(t, ids[i])
+	}
+
+	// The request ID set outside the cache isn't stored with the response.
+	assert.Equal(t, int32(1), calls.Load())
+	assert.NotEqual(t, ids[0], ids[1])
+}
+
+func TestMiddlewareNonce(t *testing.T) {
+	tests := []struct {
+		name      string
+		readNonce bool
+		wantCalls int32
+	}{
+		{"Nonce read", true, 2},
+		{"Nonce unread", false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			handler := secure.Middleware(secure.NewConfig())(
+				Middleware(NewConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					calls.Add(1)
+					w.Header().Set("Cache-Control", "max-age=60")
+					if tt.readNonce {
+						_, _ = w.Write([]byte(`<script nonce="` + secure.Nonce(r.Context()) + `"></script>`))
+					}
+				})),
+			)
+
+			for i := 0; i < 2; i++ {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+				require.Equal(t, http.StatusOK, rr.Code)
+			}
+
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestMiddlewareConditional(t *testing.T) {
+	handler := Middleware(NewConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		_, _ = w.Write([]byte("response 1"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := rr.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, "10", rr.Header().Get("Content-Length"))
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{"Matching ETag", "If-None-Match", `"other", ` + etag, http.StatusNotModified},
+		{"Weak matching ETag", "If-None-Match", "W/" + etag, http.StatusNotModified},
+		{"Wildcard", "If-None-Match", "*", http.StatusNotModified},
+		{"Other ETag", "If-None-Match", `"other"`, http.StatusOK},
+		{"Not modified since", "If-Modified-Since", "Tue, 03 Jan 2006 15:04:05 GMT", http.StatusNotModified},
+		{"Modified since", "If-Modified-Since", "Sun, 01 Jan 2006 15:04:05 GMT", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(tt.header, tt.value)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, etag, rr.Header().Get("ETag"))
+			if tt.wantStatus == http.StatusNotModified {
+				assert.Empty(t, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestMiddlewareVary(t *testing.T) {
+	handler := Middleware(NewConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = w.Write([]byte("lang " + r.Header.Get("Accept-Language")))
+	}))
+
+	for _, lang := range []string{"en", "fr", "en", "fr"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", lang)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, "lang "+lang, rr.Body.String())
+	}
+}
+
+func TestMiddlewareHead(t *testing.T) {
+	var calls atomic.Int32
+	handler := Middleware(NewConfig())(countingHandler(&calls, "max-age=60", nil))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodHead, "/", nil))
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Equal(t, "go-http; hit", rr.Header().Get("Cache-Status"))
+}
+
+func TestMiddlewareStaleWhileRevalidate(t *testing.T) {
+	var calls atomic.Int32
+	handler := Middleware(NewConfig())(countingHandler(&calls, "max-age=1, stale-while-revalidate=60", nil))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// Age the stored entry past its lifetime.
+	time.Sleep(1100 * time.Millisecond)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "response 1", rr.Body.String())
+	assert.Equal(t, "go-http; hit; fwd=stale", rr.Header().Get("Cache-Status"))
+	assert.Equal(t, "1", rr.Header().Get("Age"))
+
+	// The background revalidation refreshes the entry.
+	assert.Eventually(t, func() bool {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rr.Body.String() == "response 2" && rr.Header().Get("Cache-Status") == "go-http; hit"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMiddlewareLargeResponse(t *testing.T) {
+	config := NewConfig()
+	config.MaxBodySize = 4
+
+	var calls atomic.Int32
+	handler := Middleware(config)(countingHandler(&calls, "max-age=60", nil))
+
+	for i := 1; i <= 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, "response "+strconv.Itoa(i), rr.Body.String())
+		assert.Empty(t, rr.Header().Get("ETag"))
+	}
+}
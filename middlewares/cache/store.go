@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by stores when no entry exists for a key.
+var ErrNotFound = errors.New("cache entry not found")
+
+// Entry is a cached response. Entries with Vary only list the request headers that select the variant,
+// which is stored under a secondary key.
+type Entry struct {
+	Status               int
+	Header               http.Header
+	Body                 []byte
+	Vary                 []string
+	StoredAt             time.Time
+	Lifetime             time.Duration
+	StaleWhileRevalidate time.Duration
+}
+
+// size returns the approximate memory footprint of the entry in bytes.
+func (e *Entry) size() int64 {
+	size := int64(len(e.Body))
+	for name, values := range e.Header {
+		size += int64(len(name))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+	for _, name := range e.Vary {
+		size += int64(len(name))
+	}
+	return size
+}
+
+// age returns the time elapsed since the entry was stored.
+func (e *Entry) age(now time.Time) time.Duration {
+	return now.Sub(e.StoredAt)
+}
+
+// fresh checks if the entry can be served without revalidation.
+func (e *Entry) fresh(now time.Time) bool {
+	return e.age(now) < e.Lifetime
+}
+
+// usableStale checks if the entry can be served while being revalidated in the background.
+func (e *Entry) usableStale(now time.Time) bool {
+	return e.age(now) < e.Lifetime+e.StaleWhileRevalidate
+}
+
+// Store is an interface for cache storage backends.
+type Store interface {
+	// Get returns the entry stored under key, or ErrNotFound.
+	Get(ctx context.Context, key string) (*Entry, error)
+	// Set stores the entry under key.
+	Set(ctx context.Context, key string, entry *Entry) error
+	// Delete removes the entry stored under key.
+	Delete(ctx context.Context, key string) error
+}
+
+// LRUStore is a Store keeping entries in process memory, evicting the least recently used ones
+// once their total size exceeds a number of bytes.
+type LRUStore struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+// lruItem is an element of the LRUStore order list.
+type lruItem struct {
+	key   string
+	entry *Entry
+	size  int64
+}
+
+// NewLRUStore returns a new LRUStore holding up to maxBytes of entries.
+func NewLRUStore(maxBytes int64) *LRUStore {
+	return &LRUStore{
+		maxBytes: maxBytes,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get implements Store.
+func (s *LRUStore) Get(_ context.Context, key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.items[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	s.order.MoveToFront(element)
+	return element.Value.(*lruItem).entry, nil
+}
+
+// Set implements Store. Entries larger than the store are ignored.
+func (s *LRUStore) Set(_ context.Context, key string, entry *Entry) error {
+	size := int64(len(key)) + entry.size()
+	if size > s.maxBytes {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.items[key]; ok {
+		s.remove(element)
+	}
+	s.items[key] = s.order.PushFront(&lruItem{key: key, entry: entry, size: size})
+	s.size += size
+
+	for s.size > s.maxBytes {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *LRUStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.items[key]; ok {
+		s.remove(element)
+	}
+	return nil
+}
+
+// remove removes the element from the store.
+func (s *LRUStore) remove(element *list.Element) {
+	item := element.Value.(*lruItem)
+	s.order.Remove(element)
+	delete(s.items, item.key)
+	s.size -= item.size
+}
+
+// Size returns the total size in bytes of the stored entries.
+func (s *LRUStore) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLRUStore(300)
+	entry := func(size int) *Entry { return &Entry{Body: make([]byte, size)} }
+
+	require.NoError(t, store.Set(ctx, "a", entry(99)))
+	require.NoError(t, store.Set(ctx, "b", entry(99)))
+	require.NoError(t, store.Set(ctx, "c", entry(99)))
+	assert.Equal(t, int64(300), store.Size())
+
+	// Reading "a" makes "b" the least recently used entry.
+	_, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, store.Set(ctx, "d", entry(99)))
+
+	_, err = store.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrNotFound)
+	for _, key := range []string{"a", "c", "d"} {
+		_, err := store.Get(ctx, key)
+		assert.NoError(t, err, key)
+	}
+
+	// Entries larger than the store are ignored.
+	require.NoError(t, store.Set(ctx, "huge", entry(1000)))
+	_, err = store.Get(ctx, "huge")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Set(ctx, "a", entry(9)))
+	require.NoError(t, store.Delete(ctx, "c"))
+	assert.Equal(t, int64(110), store.Size())
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// recorder is a http.ResponseWriter buffering the response so it can be cached and answered conditionally.
+// Responses larger than the maximum body size, or flushed by the handler, are passed through instead.
+type recorder struct {
+	http.ResponseWriter
+	initialHeader http.Header
+	maxBodySize   int
+	status        int
+	body          bytes.Buffer
+	passthrough   bool
+}
+
+// newRecorder returns a new recorder writing to w.
+func newRecorder(w http.ResponseWriter, maxBodySize int) *recorder {
+	return &recorder{ResponseWriter: w, initialHeader: w.Header().Clone(), maxBodySize: maxBodySize}
+}
+
+// WriteHeader implements http.ResponseWriter and records the status code.
+func (w *recorder) WriteHeader(statusCode int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = statusCode
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(statusCode)
+	}
+}
+
+// Write implements http.ResponseWriter and buffers the body until it exceeds the maximum size.
+func (w *recorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.passthrough && w.body.Len()+len(b) > w.maxBodySize {
+		w.startPassthrough()
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+// Flush implements http.Flusher, giving up on buffering as the handler streams the response.
+func (w *recorder) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.passthrough {
+		w.startPassthrough()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// startPassthrough writes the buffered response and stops buffering.
+func (w *recorder) startPassthrough() {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	w.body.Reset()
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *recorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// discardWriter is a http.ResponseWriter discarding the response, used by background revalidations.
+type discardWriter struct {
+	header http.Header
+}
+
+// Header implements http.ResponseWriter.
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *discardWriter) WriteHeader(int) {}
+
+// Write implements http.ResponseWriter.
+func (w *discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
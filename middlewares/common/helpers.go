@@ -1,6 +1,14 @@
 package common
 
-import "strings"
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
 
 // ShouldSkip checks if the given path should be skipped based on the excluded prefixes.
 func ShouldSkip(path string, excludedPrefixes []string) bool {
@@ -11,3 +19,124 @@ func ShouldSkip(path string, excludedPrefixes []string) bool {
 	}
 	return false
 }
+
+// MatchPrefix returns the longest prefix in values that matches the given path, along with its value.
+func MatchPrefix[T any](path string, values map[string]T) (string, T, bool) {
+	var (
+		match string
+		value T
+		found bool
+	)
+
+	for prefix, v := range values {
+		if (!found || len(prefix) > len(match)) && strings.HasPrefix(path, prefix) {
+			match, value, found = prefix, v, true
+		}
+	}
+
+	return match, value, found
+}
+
+// SensitiveQueryParams lists query parameters that commonly carry credentials.
+var SensitiveQueryParams = []string{"access_token", "api_key", "apikey", "key", "token"}
+
+// RedactedURL returns the URL as a string with the values of sensitive query parameters replaced,
+// covering SensitiveQueryParams and the given extra parameters.
+func RedactedURL(u *url.URL, extraParams []string) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+
+	var (
+		query    = u.Query()
+		params   = append(append([]string{}, SensitiveQueryParams...), extraParams...)
+		redacted = false
+	)
+
+	for name := range query {
+		for _, param := range params {
+			if strings.EqualFold(name, param) {
+				query[name] = []string{"REDACTED"}
+				redacted = true
+			}
+		}
+	}
+
+	if !redacted {
+		return u.String()
+	}
+
+	clone := *u
+	clone.RawQuery = query.Encode()
+	return clone.String()
+}
+
+// CredentialHeaders are the request headers carrying the credentials of the authentication middlewares.
+var CredentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-API-Key", "X-Goog-IAP-JWT-Assertion"}
+
+// HeaderRules are changes applied to headers, such as those of proxied or mirrored requests.
+type HeaderRules struct {
+	Set    map[string]string // Headers set, replacing any value.
+	Remove []string          // Headers removed.
+}
+
+// Apply changes the header according to the rules.
+func (rules HeaderRules) Apply(h http.Header) {
+	for _, name := range rules.Remove {
+		h.Del(name)
+	}
+	for name, value := range rules.Set {
+		h.Set(name, value)
+	}
+}
+
+// AddedHeaders returns the header values of after missing from before, such as the headers set by a handler
+// on top of those set by outer middlewares.
+func AddedHeaders(before, after http.Header) http.Header {
+	added := http.Header{}
+	for name, values := range after {
+		for _, value := range values {
+			if !slices.Contains(before[name], value) {
+				added[name] = append(added[name], value)
+			}
+		}
+	}
+	return added
+}
+
+// MergeHeaders adds the header values of src missing from dst, without overwriting those already set.
+func MergeHeaders(dst, src http.Header) {
+	for name, values := range src {
+		for _, value := range values {
+			if !slices.Contains(dst[name], value) {
+				dst[name] = append(dst[name], value)
+			}
+		}
+	}
+}
+
+// Detach returns a context keeping the values of ctx, such as its span and logger attributes, but not its
+// cancellation, so that background work outlives the request.
+func Detach(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+// detachedContext is a context keeping the values of its parent, but not its cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+// Deadline implements context.Context.
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done implements context.Context.
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err implements context.Context.
+func (detachedContext) Err() error {
+	return nil
+}
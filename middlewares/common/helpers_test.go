@@ -1,6 +1,8 @@
 package common
 
 import (
+	"net/http"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -52,3 +54,68 @@ func TestShouldSkip(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchPrefix(t *testing.T) {
+	values := map[string]int{
+		"/api":        1,
+		"/api/upload": 2,
+		"/static":     3,
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantPrefix string
+		want       int
+		wantFound  bool
+	}{
+		{"No match", "/health", "", 0, false},
+		{"Single match", "/static/app.js", "/static", 3, true},
+		{"Longest match wins", "/api/upload/images", "/api/upload", 2, true},
+		{"Shorter match", "/api/users", "/api", 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, got, found := MatchPrefix(tt.path, values)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.wantPrefix, prefix)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRedactedURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		rawURL      string
+		extraParams []string
+		want        string
+	}{
+		{"No query", "/api/users", nil, "/api/users"},
+		{"No sensitive parameter", "/api/users?page=2", nil, "/api/users?page=2"},
+		{"Default sensitive parameter", "/api/users?api_key=secret&page=2", nil, "/api/users?api_key=REDACTED&page=2"},
+		{"Case insensitive", "/api/users?API_KEY=secret", nil, "/api/users?API_KEY=REDACTED"},
+		{"Extra parameter", "/api/users?sig=secret", []string{"sig"}, "/api/users?sig=REDACTED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.rawURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, RedactedURL(u, tt.extraParams))
+		})
+	}
+}
+
+func TestAddedAndMergeHeaders(t *testing.T) {
+	before := http.Header{"X-Request-Id": {"a"}, "Vary": {"Origin"}}
+	after := http.Header{"X-Request-Id": {"a"}, "Vary": {"Origin", "Accept"}, "Content-Type": {"text/plain"}}
+
+	added := AddedHeaders(before, after)
+	assert.Equal(t, http.Header{"Vary": {"Accept"}, "Content-Type": {"text/plain"}}, added)
+
+	current := http.Header{"X-Request-Id": {"b"}, "Vary": {"Origin"}}
+	MergeHeaders(current, added)
+	assert.Equal(t, http.Header{"X-Request-Id": {"b"}, "Vary": {"Origin", "Accept"}, "Content-Type": {"text/plain"}}, current)
+}
@@ -0,0 +1,69 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported content codings.
+const (
+	Brotli  = "br"
+	Deflate = "deflate"
+	Gzip    = "gzip"
+	Zstd    = "zstd"
+)
+
+// encoder is a reusable streaming compressor.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoderPools holds a pool of reusable encoders per content coding to keep allocations low.
+var encoderPools = map[string]*sync.Pool{
+	Brotli: {New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}},
+	Deflate: {New: func() any {
+		// The "deflate" content coding is the zlib format (RFC 1950), not raw deflate.
+		w, _ := zlib.NewWriterLevel(io.Discard, zlib.DefaultCompression)
+		return w
+	}},
+	Gzip: {New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}},
+	Zstd: {New: func() any {
+		w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+		return &zstdEncoder{w}
+	}},
+}
+
+// zstdEncoder adapts *zstd.Encoder to the encoder interface.
+type zstdEncoder struct {
+	*zstd.Encoder
+}
+
+// Reset implements encoder.
+func (e *zstdEncoder) Reset(w io.Writer) {
+	e.Encoder.Reset(w)
+}
+
+// getEncoder returns a pooled encoder for the given content coding writing to w.
+func getEncoder(encoding string, w io.Writer) encoder {
+	enc := encoderPools[encoding].Get().(encoder)
+	enc.Reset(w)
+	return enc
+}
+
+// putEncoder returns an encoder to its pool.
+func putEncoder(encoding string, enc encoder) {
+	enc.Reset(io.Discard)
+	encoderPools[encoding].Put(enc)
+}
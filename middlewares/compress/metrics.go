@@ -0,0 +1,42 @@
+package compress
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+)
+
+// encodingKey is the attribute key for the response content coding.
+const encodingKey = attribute.Key("http.response.content_encoding")
+
+type Metrics struct {
+	uncompressedSize metric.Int64Histogram
+	compressedSize   metric.Int64Histogram
+}
+
+// NewMetrics returns a new Metrics instance.
+func NewMetrics(meter *metric.Meter) *Metrics {
+	uncompressedSize, _ := (*meter).Int64Histogram(
+		"http_response_size_bytes",
+		metric.WithDescription("HTTP response body size in bytes before compression."),
+	)
+
+	compressedSize, _ := (*meter).Int64Histogram(
+		"http_response_compressed_size_bytes",
+		metric.WithDescription("HTTP response body size in bytes after compression."),
+	)
+
+	return &Metrics{
+		uncompressedSize: uncompressedSize,
+		compressedSize:   compressedSize,
+	}
+}
+
+// RecordResponseSizes records the response body size before and after compression.
+func (m *Metrics) RecordResponseSizes(ctx context.Context, method, encoding string, uncompressed, compressed int64) {
+	attrs := metric.WithAttributes(semconv.HTTPMethodKey.String(method), encodingKey.String(encoding))
+	m.uncompressedSize.Record(ctx, uncompressed, attrs)
+	m.compressedSize.Record(ctx, compressed, attrs)
+}
@@ -0,0 +1,107 @@
+package compress
+
+import (
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+)
+
+// Config is a struct that holds configuration options for the compression middleware.
+type Config struct {
+	Encodings    []string // Supported content codings, in order of server preference.
+	ContentTypes []string // Compressible media types, "type/*" matches a whole type.
+	MinSize      int      // Minimum response body size in bytes to compress.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		Encodings: []string{Zstd, Brotli, Gzip, Deflate},
+		ContentTypes: []string{
+			"text/*",
+			"application/javascript",
+			"application/json",
+			"application/problem+json",
+			"application/x-ndjson",
+			"application/xml",
+			"image/svg+xml",
+		},
+		MinSize: 1024,
+	}
+}
+
+// isCompressible checks if the given Content-Type header value is in the allowlist.
+func (c *Config) isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range c.ContentTypes {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware is the compression middleware function that takes a Config struct and returns the middleware.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	var (
+		pkgName = reflect.TypeOf(struct{}{}).PkgPath()
+		meter   = otel.GetMeterProvider().Meter(pkgName)
+		metrics = NewMetrics(&meter)
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addVary(w.Header(), "Accept-Encoding")
+
+			// Range requests address the identity representation, and upgraded connections are not HTTP bodies.
+			if r.Header.Get("Range") != "" || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			encoding := negotiate(r.Header.Get("Accept-Encoding"), config.Encodings)
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				config:         config,
+				method:         r.Method,
+				encoding:       encoding,
+			}
+			defer func() {
+				cw.close()
+				if cw.encoder != nil || cw.uncompressed > 0 {
+					metrics.RecordResponseSizes(r.Context(), r.Method, cw.appliedEncoding(), cw.uncompressed, cw.compressed)
+				}
+			}()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// addVary adds a value to the Vary header unless it is already listed.
+func addVary(h http.Header, value string) {
+	for _, vary := range h.Values("Vary") {
+		for _, v := range strings.Split(vary, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
@@ -0,0 +1,126 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+// decode decompresses a response body with the given content coding.
+func decode(t *testing.T, encoding string, body []byte) string {
+	var (
+		r   io.Reader
+		err error
+	)
+
+	switch encoding {
+	case Gzip:
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case Deflate:
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	case Brotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case Zstd:
+		var d *zstd.Decoder
+		d, err = zstd.NewReader(bytes.NewReader(body))
+		r = d
+	default:
+		return string(body)
+	}
+	assert.NoError(t, err)
+
+	decoded, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(decoded)
+}
+
+func TestMiddleware(t *testing.T) {
+	var (
+		large = strings.Repeat("compressible content ", 100)
+		small = "tiny"
+	)
+
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		requestHeaders map[string]string
+		contentType    string
+		contentEnc     string
+		statusCode     int
+		body           string
+		wantEncoding   string
+	}{
+		{"Gzip", http.MethodGet, "gzip", nil, "text/plain", "", http.StatusOK, large, Gzip},
+		{"Deflate", http.MethodGet, "deflate", nil, "application/json", "", http.StatusOK, large, Deflate},
+		{"Brotli", http.MethodGet, "br", nil, "text/html; charset=utf-8", "", http.StatusOK, large, Brotli},
+		{"Zstd", http.MethodGet, "zstd, gzip;q=0.5", nil, "text/plain", "", http.StatusOK, large, Zstd},
+		{"Below threshold", http.MethodGet, "gzip", nil, "text/plain", "", http.StatusOK, small, ""},
+		{"Not in allowlist", http.MethodGet, "gzip", nil, "image/png", "", http.StatusOK, large, ""},
+		{"No accepted encoding", http.MethodGet, "", nil, "text/plain", "", http.StatusOK, large, ""},
+		{"Already encoded", http.MethodGet, "gzip", nil, "text/plain", "br", http.StatusOK, large, "br"},
+		{"Range request", http.MethodGet, "gzip", map[string]string{"Range": "bytes=0-10"}, "text/plain", "", http.StatusOK, large, ""},
+		{"Sniffed content type", http.MethodGet, "gzip", nil, "", "", http.StatusOK, large, Gzip},
+		{"HEAD request", http.MethodHead, "gzip", nil, "text/plain", "", http.StatusOK, "", ""},
+		{"No content", http.MethodGet, "gzip", nil, "text/plain", "", http.StatusNoContent, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				if tt.contentEnc != "" {
+					w.Header().Set("Content-Encoding", tt.contentEnc)
+				}
+				w.WriteHeader(tt.statusCode)
+				io.WriteString(w, tt.body)
+			})
+
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			for key, value := range tt.requestHeaders {
+				req.Header.Set(key, value)
+			}
+			rr := httptest.NewRecorder()
+
+			Middleware(NewConfig())(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.statusCode, rr.Code)
+			assert.Equal(t, tt.wantEncoding, rr.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+			if tt.contentEnc == "" {
+				assert.Equal(t, tt.body, decode(t, tt.wantEncoding, rr.Body.Bytes()))
+			}
+		})
+	}
+}
+
+func TestMiddlewareFlush(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "data: second\n\n")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	Middleware(NewConfig())(handler).ServeHTTP(rr, req)
+
+	assert.True(t, rr.Flushed)
+	assert.Equal(t, Gzip, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "data: first\n\ndata: second\n\n", decode(t, Gzip, rr.Body.Bytes()))
+}
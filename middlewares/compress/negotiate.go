@@ -0,0 +1,69 @@
+package compress
+
+import (
+	"strconv"
+	"strings"
+)
+
+// negotiate returns the supported encoding preferred by the client according to the Accept-Encoding header.
+// Ties in quality values are broken by the order of supported encodings. An empty string means no encoding.
+func negotiate(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qualities := parseAcceptEncoding(acceptEncoding)
+
+	var (
+		best                  string
+		bestQ                 float64
+		wildcard, hasWildcard = qualities["*"]
+	)
+
+	for _, encoding := range supported {
+		q, ok := qualities[encoding]
+		if !ok && hasWildcard {
+			q, ok = wildcard, true
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+// parseAcceptEncoding parses an Accept-Encoding header into a map of content codings to quality values.
+func parseAcceptEncoding(header string) map[string]float64 {
+	qualities := make(map[string]float64)
+
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		// Legacy aliases are equivalent to their standard names.
+		if coding == "x-gzip" {
+			coding = "gzip"
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || !strings.EqualFold(key, "q") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				parsed = 0
+			}
+			q = parsed
+		}
+
+		qualities[coding] = q
+	}
+
+	return qualities
+}
@@ -0,0 +1,35 @@
+package compress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	supported := []string{Zstd, Brotli, Gzip, Deflate}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{"No header", "", ""},
+		{"Single encoding", "gzip", Gzip},
+		{"Server preference on ties", "gzip, br", Brotli},
+		{"Higher quality wins", "br;q=0.5, gzip;q=0.8", Gzip},
+		{"Zero quality excluded", "zstd;q=0, br;q=0", ""},
+		{"Wildcard", "*", Zstd},
+		{"Wildcard with exclusion", "*;q=0.5, zstd;q=0", Brotli},
+		{"Legacy alias", "x-gzip", Gzip},
+		{"Unsupported only", "compress", ""},
+		{"Identity only", "identity", ""},
+		{"Case insensitive", "GZIP;Q=1", Gzip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, negotiate(tt.acceptEncoding, supported))
+		})
+	}
+}
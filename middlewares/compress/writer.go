@@ -0,0 +1,200 @@
+package compress
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressWriter buffers the start of a response to decide whether to compress it, then streams it through an encoder.
+type compressWriter struct {
+	http.ResponseWriter
+	config   *Config
+	method   string
+	encoding string
+
+	buf         []byte
+	statusCode  int
+	wroteHeader bool // The handler called WriteHeader.
+	decided     bool // Headers were sent downstream.
+	encoder     encoder
+
+	uncompressed int64
+	compressed   int64
+}
+
+// WriteHeader implements http.ResponseWriter and defers sending headers until the compression decision is made.
+func (w *compressWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+
+	// Informational responses are forwarded as is.
+	if statusCode >= 100 && statusCode < 200 {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	w.wroteHeader = true
+	w.statusCode = statusCode
+
+	// Responses that can never carry a compressible body are sent right away.
+	if !bodyAllowed(w.method, statusCode) {
+		w.decide(false)
+	}
+}
+
+// Write implements http.ResponseWriter.
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	w.uncompressed += int64(len(b))
+
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.config.MinSize {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.writeIdentity(b)
+}
+
+// writeIdentity writes uncompressed data downstream.
+func (w *compressWriter) writeIdentity(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.compressed += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher and forces the compression decision for streamed responses.
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.decided {
+		_ = w.decide(true)
+	}
+
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close finishes the response, sending buffered data that never reached the compression threshold as is.
+func (w *compressWriter) close() {
+	if !w.wroteHeader {
+		return
+	}
+
+	if !w.decided {
+		_ = w.decide(false)
+	}
+
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		putEncoder(w.encoding, w.encoder)
+	}
+}
+
+// appliedEncoding returns the content coding actually used for the response.
+func (w *compressWriter) appliedEncoding() string {
+	if w.encoder != nil {
+		return w.encoding
+	}
+	return "identity"
+}
+
+// decide sends the headers downstream, choosing whether to compress, and writes the buffered data.
+func (w *compressWriter) decide(enoughData bool) error {
+	w.decided = true
+	h := w.Header()
+
+	// Sniff the content type now, as net/http would otherwise sniff the compressed bytes.
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if enoughData && w.shouldCompress(h) {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+
+		// The compressed representation is not byte-for-byte identical anymore.
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+
+		w.ResponseWriter.WriteHeader(w.statusCode)
+		w.encoder = getEncoder(w.encoding, &countingWriter{w: w.ResponseWriter, n: &w.compressed})
+		_, err := w.encoder.Write(w.buf)
+		w.buf = nil
+		return err
+	}
+
+	w.ResponseWriter.WriteHeader(w.statusCode)
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	_, err := w.writeIdentity(w.buf)
+	w.buf = nil
+	return err
+}
+
+// shouldCompress checks if the response described by the headers is eligible for compression.
+func (w *compressWriter) shouldCompress(h http.Header) bool {
+	if !bodyAllowed(w.method, w.statusCode) || w.statusCode == http.StatusPartialContent {
+		return false
+	}
+
+	// Already encoded or partial responses are left untouched.
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+
+	if cl, err := strconv.Atoi(h.Get("Content-Length")); err == nil && cl < w.config.MinSize {
+		return false
+	}
+
+	return w.config.isCompressible(h.Get("Content-Type"))
+}
+
+// bodyAllowed checks if a response to the given method with the given status code can carry a body.
+func bodyAllowed(method string, statusCode int) bool {
+	if method == http.MethodHead {
+		return false
+	}
+	return statusCode != http.StatusNoContent && statusCode != http.StatusNotModified
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+// Write implements io.Writer.
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	*c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,125 @@
+package concurrency
+
+import (
+	"math"
+	"time"
+)
+
+// Algorithm is an adaptive concurrency limit algorithm.
+// Implementations are not safe for concurrent use, the Limiter serializes calls.
+type Algorithm interface {
+	// Limit returns the current concurrency limit.
+	Limit() int
+	// Update adjusts the limit from a completed request's latency, the number of in-flight requests
+	// when it started, and whether it was dropped (timed out or failed because of overload).
+	Update(rtt time.Duration, inflight int, dropped bool)
+}
+
+// AIMD is an additive increase, multiplicative decrease Algorithm.
+// The limit grows by one while requests succeed and shrinks by BackoffRatio when one is dropped or slower than Timeout.
+type AIMD struct {
+	MinLimit     int           // Lower bound of the limit.
+	MaxLimit     int           // Upper bound of the limit.
+	BackoffRatio float64       // Factor applied to the limit on drops, between 0 and 1.
+	Timeout      time.Duration // Latency above which a request counts as dropped.
+
+	limit int
+}
+
+// NewAIMD returns a new AIMD starting at initialLimit with default bounds.
+func NewAIMD(initialLimit int) *AIMD {
+	return &AIMD{
+		MinLimit:     1,
+		MaxLimit:     1000,
+		BackoffRatio: 0.9,
+		Timeout:      5 * time.Second,
+		limit:        initialLimit,
+	}
+}
+
+// Limit implements Algorithm.
+func (a *AIMD) Limit() int {
+	return a.limit
+}
+
+// Update implements Algorithm.
+func (a *AIMD) Update(rtt time.Duration, inflight int, dropped bool) {
+	switch {
+	case dropped || rtt > a.Timeout:
+		a.limit = int(float64(a.limit) * a.BackoffRatio)
+	case inflight*2 >= a.limit:
+		// Only grow when the limit is actually being used.
+		a.limit++
+	}
+
+	a.limit = clamp(a.limit, a.MinLimit, a.MaxLimit)
+}
+
+// Gradient is an Algorithm adjusting the limit from the ratio between the long-term and the current latency,
+// in the spirit of Netflix's Gradient2 and TCP Vegas. Growing latency means queueing, so the limit shrinks.
+type Gradient struct {
+	MinLimit  int     // Lower bound of the limit.
+	MaxLimit  int     // Upper bound of the limit.
+	Tolerance float64 // Latency growth tolerated before shrinking the limit, 2 tolerates doubling.
+	Smoothing float64 // Weight of a new limit estimate, between 0 and 1.
+	LongAlpha float64 // Weight of a sample in the long-term latency average, between 0 and 1.
+
+	limit   float64
+	longRTT float64
+}
+
+// NewGradient returns a new Gradient starting at initialLimit with default parameters.
+func NewGradient(initialLimit int) *Gradient {
+	return &Gradient{
+		MinLimit:  1,
+		MaxLimit:  1000,
+		Tolerance: 1.5,
+		Smoothing: 0.2,
+		LongAlpha: 2.0 / 601, // Exponential average over about 600 samples.
+		limit:     float64(initialLimit),
+	}
+}
+
+// Limit implements Algorithm.
+func (g *Gradient) Limit() int {
+	return int(g.limit)
+}
+
+// Update implements Algorithm.
+func (g *Gradient) Update(rtt time.Duration, inflight int, _ bool) {
+	shortRTT := float64(rtt)
+	if shortRTT <= 0 {
+		return
+	}
+
+	if g.longRTT == 0 {
+		g.longRTT = shortRTT
+	} else {
+		g.longRTT = g.longRTT*(1-g.LongAlpha) + shortRTT*g.LongAlpha
+	}
+
+	// Don't grow the limit when it is not being used.
+	if float64(inflight)*2 < g.limit {
+		return
+	}
+
+	var (
+		gradient  = math.Max(0.5, math.Min(1, g.Tolerance*g.longRTT/shortRTT))
+		queueSize = math.Sqrt(g.limit)
+		newLimit  = g.limit*gradient + queueSize
+	)
+
+	g.limit = g.limit*(1-g.Smoothing) + newLimit*g.Smoothing
+	g.limit = math.Max(float64(g.MinLimit), math.Min(float64(g.MaxLimit), g.limit))
+}
+
+// clamp returns v bounded by lo and hi.
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,63 @@
+package concurrency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAIMD(t *testing.T) {
+	tests := []struct {
+		name      string
+		rtt       time.Duration
+		inflight  int
+		dropped   bool
+		wantLimit int
+	}{
+		{"Increase when used", 10 * time.Millisecond, 5, false, 11},
+		{"Hold when unused", 10 * time.Millisecond, 2, false, 10},
+		{"Decrease on drop", 10 * time.Millisecond, 5, true, 9},
+		{"Decrease on timeout", 10 * time.Second, 5, false, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aimd := NewAIMD(10)
+			aimd.Update(tt.rtt, tt.inflight, tt.dropped)
+			assert.Equal(t, tt.wantLimit, aimd.Limit())
+		})
+	}
+}
+
+func TestAIMDBounds(t *testing.T) {
+	aimd := NewAIMD(2)
+	aimd.MaxLimit = 3
+
+	for i := 0; i < 10; i++ {
+		aimd.Update(time.Millisecond, 3, false)
+	}
+	assert.Equal(t, 3, aimd.Limit())
+
+	for i := 0; i < 10; i++ {
+		aimd.Update(time.Millisecond, 3, true)
+	}
+	assert.Equal(t, 1, aimd.Limit())
+}
+
+func TestGradient(t *testing.T) {
+	gradient := NewGradient(20)
+
+	// Stable latency grows the limit.
+	for i := 0; i < 50; i++ {
+		gradient.Update(10*time.Millisecond, gradient.Limit(), false)
+	}
+	grown := gradient.Limit()
+	assert.Greater(t, grown, 20)
+
+	// Latency well above the long-term average shrinks it.
+	for i := 0; i < 50; i++ {
+		gradient.Update(100*time.Millisecond, gradient.Limit(), false)
+	}
+	assert.Less(t, gradient.Limit(), grown)
+}
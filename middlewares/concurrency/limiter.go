@@ -0,0 +1,115 @@
+package concurrency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter bounds the number of in-flight requests with an adaptive limit and an optional bounded wait queue.
+type Limiter struct {
+	mu        sync.Mutex
+	algorithm Algorithm
+	maxQueue  int
+	inflight  int
+	waiters   list.List
+}
+
+// NewLimiter returns a new Limiter driven by the algorithm, queueing up to maxQueue requests.
+func NewLimiter(algorithm Algorithm, maxQueue int) *Limiter {
+	return &Limiter{
+		algorithm: algorithm,
+		maxQueue:  maxQueue,
+	}
+}
+
+// Token is a granted in-flight slot, to be released when the request completes.
+type Token struct {
+	limiter  *Limiter
+	start    time.Time
+	inflight int
+}
+
+// Acquire grants an in-flight slot, waiting in the queue until ctx is done if the limit is reached.
+// It returns nil when the request must be shed.
+func (l *Limiter) Acquire(ctx context.Context) *Token {
+	l.mu.Lock()
+	if l.inflight < l.algorithm.Limit() && l.waiters.Len() == 0 {
+		token := l.grant()
+		l.mu.Unlock()
+		return token
+	}
+
+	if l.waiters.Len() >= l.maxQueue {
+		l.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan *Token, 1)
+	elem := l.waiters.PushBack(ready)
+	l.mu.Unlock()
+
+	select {
+	case token := <-ready:
+		return token
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		select {
+		case <-ready:
+			// Granted after the context was done, hand the slot over.
+			l.inflight--
+			l.notifyWaiters()
+		default:
+			l.waiters.Remove(elem)
+		}
+		return nil
+	}
+}
+
+// Release returns the slot and feeds the request's latency to the algorithm.
+func (t *Token) Release(dropped bool) {
+	l := t.limiter
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+	l.algorithm.Update(time.Since(t.start), t.inflight, dropped)
+	l.notifyWaiters()
+}
+
+// Limit returns the current concurrency limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.algorithm.Limit()
+}
+
+// Inflight returns the number of in-flight requests.
+func (l *Limiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+// grant takes a slot, the lock must be held.
+func (l *Limiter) grant() *Token {
+	l.inflight++
+	return &Token{limiter: l, start: time.Now(), inflight: l.inflight}
+}
+
+// notifyWaiters grants slots to queued requests in order while under the limit, the lock must be held.
+func (l *Limiter) notifyWaiters() {
+	for l.inflight < l.algorithm.Limit() {
+		next := l.waiters.Front()
+		if next == nil {
+			return
+		}
+
+		l.waiters.Remove(next)
+		next.Value.(chan *Token) <- l.grant()
+	}
+}
@@ -0,0 +1,47 @@
+package concurrency
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+)
+
+type Metrics struct {
+	shedCounter metric.Int64Counter
+}
+
+// NewMetrics returns a new Metrics instance observing the limiter's current limit and in-flight requests.
+func NewMetrics(meter *metric.Meter, limiter *Limiter) *Metrics {
+	shedCounter, _ := (*meter).Int64Counter(
+		"http_requests_shed_total",
+		metric.WithDescription("Total number of HTTP requests shed by concurrency limiting."),
+	)
+
+	_, _ = (*meter).Int64ObservableGauge(
+		"http_concurrency_limit",
+		metric.WithDescription("Current adaptive limit of concurrent HTTP requests."),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(limiter.Limit()))
+			return nil
+		}),
+	)
+
+	_, _ = (*meter).Int64ObservableGauge(
+		"http_requests_inflight",
+		metric.WithDescription("Current number of in-flight HTTP requests."),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(limiter.Inflight()))
+			return nil
+		}),
+	)
+
+	return &Metrics{
+		shedCounter: shedCounter,
+	}
+}
+
+// IncreaseShedCounter increases the shed request counter by 1.
+func (m *Metrics) IncreaseShedCounter(ctx context.Context, method string) {
+	m.shedCounter.Add(ctx, 1, metric.WithAttributes(semconv.HTTPMethodKey.String(method)))
+}
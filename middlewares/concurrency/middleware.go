@@ -0,0 +1,97 @@
+package concurrency
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// Config is a struct that holds configuration options for the concurrency limit middleware.
+type Config struct {
+	Algorithm        Algorithm     // Adaptive limit algorithm.
+	MaxQueue         int           // Maximum number of requests waiting for a slot, 0 disables queueing.
+	QueueTimeout     time.Duration // Maximum time a request waits in the queue.
+	CriticalPrefixes []string      // Path prefixes that are never shed, such as health checks.
+	RetryAfter       time.Duration // Delay advertised to shed clients.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		Algorithm:        NewAIMD(20),
+		MaxQueue:         0,
+		QueueTimeout:     time.Second,
+		CriticalPrefixes: []string{},
+		RetryAfter:       time.Second,
+	}
+}
+
+// Middleware is the concurrency limit middleware function that takes a Config struct and returns the middleware.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	var (
+		limiter = NewLimiter(config.Algorithm, config.MaxQueue)
+		pkgName = reflect.TypeOf(struct{}{}).PkgPath()
+		meter   = otel.GetMeterProvider().Meter(pkgName)
+		metrics = NewMetrics(&meter, limiter)
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.CriticalPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), config.QueueTimeout)
+			token := limiter.Acquire(ctx)
+			cancel()
+
+			if token == nil {
+				slog.Error("request shed",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("limit", limiter.Limit()),
+				)
+				metrics.IncreaseShedCounter(r.Context(), r.Method)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(config.RetryAfter.Seconds()))))
+				problem.Error(w, http.StatusServiceUnavailable, "server overloaded")
+				return
+			}
+
+			wrappedWriter := &statusWriter{ResponseWriter: w}
+			defer func() {
+				// Overload shows up as timeouts and unavailable upstreams.
+				token.Release(wrappedWriter.statusCode == http.StatusServiceUnavailable ||
+					wrappedWriter.statusCode == http.StatusGatewayTimeout ||
+					r.Context().Err() != nil)
+			}()
+
+			next.ServeHTTP(wrappedWriter, r)
+		})
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+// WriteHeader implements http.ResponseWriter and records the status code.
+func (w *statusWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,114 @@
+package concurrency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixed is an Algorithm with a constant limit.
+type fixed int
+
+// Limit implements Algorithm.
+func (f fixed) Limit() int { return int(f) }
+
+// Update implements Algorithm.
+func (fixed) Update(time.Duration, int, bool) {}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxQueue   int
+		path       string
+		wantStatus int
+	}{
+		{"Shed without queue", 0, "/api", http.StatusServiceUnavailable},
+		{"Shed after queue timeout", 1, "/api", http.StatusServiceUnavailable},
+		{"Critical route never shed", 0, "/health", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.Algorithm = fixed(1)
+			config.MaxQueue = tt.maxQueue
+			config.QueueTimeout = 10 * time.Millisecond
+			config.CriticalPrefixes = []string{"/health"}
+
+			var (
+				entered = make(chan struct{}, 1)
+				unblock = make(chan struct{})
+				handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path == "/slow" {
+						entered <- struct{}{}
+						<-unblock
+					}
+				})
+				middleware = Middleware(config)(handler)
+				wg         sync.WaitGroup
+			)
+
+			// Hold the only slot.
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+			}()
+			<-entered
+
+			rr := httptest.NewRecorder()
+			middleware.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusServiceUnavailable {
+				assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+			}
+
+			close(unblock)
+			wg.Wait()
+		})
+	}
+}
+
+func TestMiddlewareQueue(t *testing.T) {
+	config := NewConfig()
+	config.Algorithm = fixed(1)
+	config.MaxQueue = 1
+	config.QueueTimeout = time.Second
+
+	var (
+		entered = make(chan struct{}, 1)
+		unblock = make(chan struct{})
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				entered <- struct{}{}
+				<-unblock
+			}
+		})
+		middleware = Middleware(config)(handler)
+		wg         sync.WaitGroup
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-entered
+
+	// The queued request is served once the slot is released.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(unblock)
+	}()
+
+	rr := httptest.NewRecorder()
+	middleware.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	wg.Wait()
+}
@@ -2,10 +2,14 @@ package cors
 
 import (
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 
+	"go.opentelemetry.io/otel"
 	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/problem"
 )
 
 // Config is a struct that holds configuration options for the CORS middleware.
@@ -37,8 +41,21 @@ func alwaysAllow(_ string) bool {
 	return true
 }
 
+// Rejection reasons, used as metric attribute values.
+const (
+	reasonOrigin  = "origin"
+	reasonMethod  = "method"
+	reasonHeaders = "headers"
+)
+
 // Middleware is the CORS middleware function that takes a Config struct and returns the middleware.
 func Middleware(config *Config) func(http.Handler) http.Handler {
+	var (
+		pkgName = reflect.TypeOf(struct{}{}).PkgPath()
+		meter   = otel.GetMeterProvider().Meter(pkgName)
+		metrics = NewMetrics(&meter)
+	)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
@@ -52,7 +69,8 @@ func Middleware(config *Config) func(http.Handler) http.Handler {
 			// Validate the origin using the custom validation function.
 			if !config.ValidateOrigin(origin) {
 				slog.Error("request from origin not allowed", slog.String("origin", origin))
-				w.WriteHeader(http.StatusForbidden)
+				metrics.IncreaseRejectedCounter(r.Context(), r.Method, reasonOrigin)
+				problem.Error(w, http.StatusForbidden, "origin "+origin+" not allowed")
 				return
 			}
 
@@ -73,7 +91,8 @@ func Middleware(config *Config) func(http.Handler) http.Handler {
 				// Validate the requested method.
 				if !contains(config.AllowedMethods, method) {
 					slog.Error("request method not allowed", slog.String("method", method))
-					w.WriteHeader(http.StatusMethodNotAllowed)
+					metrics.IncreaseRejectedCounter(r.Context(), r.Method, reasonMethod)
+					problem.Error(w, http.StatusMethodNotAllowed, "method "+method+" not allowed")
 					return
 				}
 
@@ -92,7 +111,8 @@ func Middleware(config *Config) func(http.Handler) http.Handler {
 				// Validate the requested headers using the custom validation function.
 				if !validateHeaders(config.ValidateHeader, requestedHeaders) {
 					slog.Error("request headers not allowed", slog.String("headers", requestedHeaders))
-					w.WriteHeader(http.StatusForbidden)
+					metrics.IncreaseRejectedCounter(r.Context(), r.Method, reasonHeaders)
+					problem.Error(w, http.StatusForbidden, "headers "+requestedHeaders+" not allowed")
 					return
 				}
 
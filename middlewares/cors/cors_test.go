@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/2n3g5c9/go-http/middlewares/problem"
 )
 
 func TestContains(t *testing.T) {
@@ -166,6 +168,9 @@ func TestMiddleware(t *testing.T) {
 			middleware(testHandler).ServeHTTP(rr, req)
 
 			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus >= http.StatusBadRequest {
+				assert.Equal(t, problem.ContentType, rr.Header().Get("Content-Type"))
+			}
 
 			if tt.wantHeaders != nil {
 				for key, wantValue := range tt.wantHeaders {
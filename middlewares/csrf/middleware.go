@@ -0,0 +1,235 @@
+package csrf
+
+import (
+	"context"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"reflect"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+	"github.com/2n3g5c9/go-http/middlewares/auth/apikey"
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// Mode is the token pattern used when browsers don't send Fetch Metadata headers.
+type Mode int
+
+const (
+	// DoubleSubmit compares the submitted token with a random token stored in a cookie.
+	DoubleSubmit Mode = iota
+	// Synchronizer compares the submitted token with a token derived from the session ID.
+	Synchronizer
+)
+
+// SessionIDFunc returns the session ID of the request, if any.
+type SessionIDFunc func(r *http.Request) (string, bool)
+
+// Config is a struct that holds configuration options for the CSRF middleware.
+type Config struct {
+	TrustedOrigins   []string      // Origins allowed to send cross-site requests, such as the CORS allowed origins.
+	Mode             Mode          // Token pattern used as a fallback for browsers without Fetch Metadata.
+	Secret           []byte        // Secret deriving synchronizer tokens, required by the Synchronizer mode.
+	SessionID        SessionIDFunc // Function returning the session ID, required by the Synchronizer mode.
+	CookieName       string        // Name of the double-submit cookie, the "__Host-" prefix requires CookieSecure.
+	CookieSecure     bool          // Flag to only send the double-submit cookie over HTTPS.
+	HeaderName       string        // Header carrying the submitted token.
+	FormField        string        // Form field carrying the submitted token.
+	ExcludedPrefixes []string      // Path prefixes that are not protected.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		TrustedOrigins:   []string{},
+		Mode:             DoubleSubmit,
+		Secret:           nil,
+		SessionID:        nil,
+		CookieName:       "__Host-csrf",
+		CookieSecure:     true,
+		HeaderName:       "X-CSRF-Token",
+		FormField:        "csrf_token",
+		ExcludedPrefixes: []string{},
+	}
+}
+
+// Rejection reasons, used as metric attribute values.
+const (
+	reasonCrossSite    = "cross_site"
+	reasonOrigin       = "untrusted_origin"
+	reasonMissingToken = "missing_token"
+	reasonInvalidToken = "invalid_token"
+)
+
+type tokenKey struct{}
+
+// tokenValue is the expected token of a request and the form field it is submitted with.
+type tokenValue struct {
+	token     []byte
+	formField string
+}
+
+// Token returns a masked CSRF token to submit with the request header or form field, or an empty string if the
+// request has none, for instance with the Synchronizer mode and no session. It changes on every call.
+func Token(r *http.Request) string {
+	value, ok := r.Context().Value(tokenKey{}).(*tokenValue)
+	if !ok || value.token == nil {
+		return ""
+	}
+	return mask(value.token)
+}
+
+// TemplateField returns a hidden form input carrying the CSRF token, to render in html/template forms.
+func TemplateField(r *http.Request) template.HTML {
+	value, ok := r.Context().Value(tokenKey{}).(*tokenValue)
+	if !ok || value.token == nil {
+		return ""
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(value.formField) +
+		`" value="` + mask(value.token) + `">`)
+}
+
+// Middleware is the CSRF middleware function that takes a Config struct and returns the middleware.
+// Unsafe requests are accepted when Fetch Metadata shows they are same-origin, or come from a trusted origin.
+// Otherwise, they must carry a valid token. Requests authenticated with an API key are exempt.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	var (
+		pkgName = reflect.TypeOf(struct{}{}).PkgPath()
+		meter   = otel.GetMeterProvider().Meter(pkgName)
+		metrics = NewMetrics(&meter)
+	)
+
+	reject := func(w http.ResponseWriter, r *http.Request, reason, detail string) {
+		slog.Error("request rejected by CSRF protection",
+			slog.String("reason", reason),
+			slog.String("origin", r.Header.Get("Origin")),
+			slog.String("path", r.URL.Path))
+		metrics.IncreaseRejectedCounter(r.Context(), r.Method, reason)
+		problem.Error(w, http.StatusForbidden, detail)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			expected := expectedToken(w, r, config)
+			r = r.WithContext(context.WithValue(r.Context(), tokenKey{}, &tokenValue{token: expected, formField: config.FormField}))
+
+			if safeMethod(r.Method) || apiKeyAuthenticated(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			origin := r.Header.Get("Origin")
+			switch r.Header.Get("Sec-Fetch-Site") {
+			case "same-origin", "none":
+				next.ServeHTTP(w, r)
+				return
+			case "same-site", "cross-site":
+				if !trusted(origin, config.TrustedOrigins) {
+					reject(w, r, reasonCrossSite, "cross-site request forbidden")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Without Fetch Metadata, reject untrusted origins and fall back to tokens.
+			if origin != "" && origin != requestOrigin(r) && !trusted(origin, config.TrustedOrigins) {
+				reject(w, r, reasonOrigin, "origin "+origin+" not allowed")
+				return
+			}
+
+			submitted := r.Header.Get(config.HeaderName)
+			if submitted == "" && config.FormField != "" {
+				submitted = r.PostFormValue(config.FormField)
+			}
+			switch {
+			case submitted == "" || expected == nil:
+				reject(w, r, reasonMissingToken, "missing CSRF token")
+				return
+			case !validToken(expected, submitted):
+				reject(w, r, reasonInvalidToken, "invalid CSRF token")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// expectedToken returns the token the request must submit, issuing a double-submit cookie when missing.
+func expectedToken(w http.ResponseWriter, r *http.Request, config *Config) []byte {
+	if config.Mode == Synchronizer {
+		if config.SessionID == nil {
+			return nil
+		}
+		sessionID, ok := config.SessionID(r)
+		if !ok || sessionID == "" {
+			return nil
+		}
+		return sessionToken(config.Secret, sessionID)
+	}
+
+	if cookie, err := r.Cookie(config.CookieName); err == nil {
+		if token, err := base64.RawURLEncoding.DecodeString(cookie.Value); err == nil && len(token) == tokenLength {
+			return token
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		slog.Error("failed to generate CSRF token", slog.String("error", err.Error()))
+		return nil
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.CookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(token),
+		Path:     "/",
+		Secure:   config.CookieSecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+// safeMethod checks if the method is safe as defined by RFC 9110, and thus not subject to CSRF.
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// apiKeyAuthenticated checks if the caller was authenticated with an API key, which browsers don't send ambiently.
+func apiKeyAuthenticated(r *http.Request) bool {
+	identity, ok := auth.IdentityFromContext(r.Context())
+	return ok && identity.Method == apikey.Method
+}
+
+// requestOrigin returns the origin the request was sent to.
+func requestOrigin(r *http.Request) string {
+	scheme := r.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + r.Host
+}
+
+// trusted checks if the origin is one of the trusted origins.
+func trusted(origin string, trustedOrigins []string) bool {
+	return origin != "" && slices.Contains(trustedOrigins, origin)
+}
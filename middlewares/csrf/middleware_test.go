@@ -0,0 +1,157 @@
+package csrf
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+	"github.com/2n3g5c9/go-http/middlewares/auth/apikey"
+)
+
+func TestMiddleware(t *testing.T) {
+	config := NewConfig()
+	config.TrustedOrigins = []string{"https://app.example.com"}
+	config.ExcludedPrefixes = []string{"/webhooks"}
+
+	token, err := newToken()
+	require.NoError(t, err)
+	cookie := &http.Cookie{Name: config.CookieName, Value: base64.RawURLEncoding.EncodeToString(token)}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		fetchSite  string
+		origin     string
+		token      string
+		form       bool
+		apiKey     bool
+		wantStatus int
+	}{
+		{"Safe method", http.MethodGet, "/orders", "cross-site", "https://evil.example", "", false, false, http.StatusOK},
+		{"Same-origin fetch", http.MethodPost, "/orders", "same-origin", "http://example.com", "", false, false, http.StatusOK},
+		{"User-initiated navigation", http.MethodPost, "/orders", "none", "", "", false, false, http.StatusOK},
+		{"Cross-site fetch", http.MethodPost, "/orders", "cross-site", "https://evil.example", "", false, false, http.StatusForbidden},
+		{"Cross-site fetch from trusted origin", http.MethodPost, "/orders", "cross-site", "https://app.example.com", "", false, false, http.StatusOK},
+		{"Same-site fetch from untrusted origin", http.MethodPost, "/orders", "same-site", "https://other.example.com", "", false, false, http.StatusForbidden},
+		{"Legacy browser with header token", http.MethodPost, "/orders", "", "", mask(token), false, false, http.StatusOK},
+		{"Legacy browser with form token", http.MethodPost, "/orders", "", "http://example.com", mask(token), true, false, http.StatusOK},
+		{"Legacy browser without token", http.MethodPost, "/orders", "", "", "", false, false, http.StatusForbidden},
+		{"Legacy browser with invalid token", http.MethodPost, "/orders", "", "", mask(make([]byte, tokenLength)), false, false, http.StatusForbidden},
+		{"Legacy browser from untrusted origin", http.MethodPost, "/orders", "", "https://evil.example", mask(token), false, false, http.StatusForbidden},
+		{"API key authenticated", http.MethodPost, "/orders", "cross-site", "https://evil.example", "", false, true, http.StatusOK},
+		{"Excluded path", http.MethodPost, "/webhooks/stripe", "cross-site", "https://evil.example", "", false, false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			var req *http.Request
+			if tt.form {
+				body := url.Values{config.FormField: {tt.token}}.Encode()
+				req = httptest.NewRequest(tt.method, tt.target, strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = httptest.NewRequest(tt.method, tt.target, nil)
+				if tt.token != "" {
+					req.Header.Set(config.HeaderName, tt.token)
+				}
+			}
+			req.AddCookie(cookie)
+			if tt.fetchSite != "" {
+				req.Header.Set("Sec-Fetch-Site", tt.fetchSite)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.apiKey {
+				req = auth.WithIdentity(req, &auth.Identity{Subject: "billing", Method: apikey.Method})
+			}
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
+
+func TestMiddlewareIssuesCookie(t *testing.T) {
+	config := NewConfig()
+
+	var rendered string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rendered = Token(r)
+		assert.Contains(t, string(TemplateField(r)), `name="csrf_token"`)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/form", nil)
+	rr := httptest.NewRecorder()
+	Middleware(config)(handler).ServeHTTP(rr, req)
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "__Host-csrf", cookies[0].Name)
+	assert.True(t, cookies[0].Secure)
+	assert.True(t, cookies[0].HttpOnly)
+
+	// The rendered token is accepted alongside the issued cookie.
+	req = httptest.NewRequest(http.MethodPost, "/form", nil)
+	req.AddCookie(cookies[0])
+	req.Header.Set(config.HeaderName, rendered)
+	rr = httptest.NewRecorder()
+	Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Result().Cookies())
+}
+
+func TestMiddlewareSynchronizer(t *testing.T) {
+	config := NewConfig()
+	config.Mode = Synchronizer
+	config.Secret = []byte("secret")
+	config.SessionID = func(r *http.Request) (string, bool) {
+		cookie, err := r.Cookie("session")
+		if err != nil {
+			return "", false
+		}
+		return cookie.Value, true
+	}
+
+	tests := []struct {
+		name       string
+		session    string
+		token      string
+		wantStatus int
+	}{
+		{"Token of the session", "s1", mask(sessionToken(config.Secret, "s1")), http.StatusOK},
+		{"Token of another session", "s1", mask(sessionToken(config.Secret, "s2")), http.StatusForbidden},
+		{"No session", "", mask(sessionToken(config.Secret, "s1")), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+			if tt.session != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: tt.session})
+			}
+			req.Header.Set(config.HeaderName, tt.token)
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Empty(t, rr.Result().Cookies())
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// tokenLength is the length in bytes of unmasked tokens.
+const tokenLength = 32
+
+// newToken returns a random token.
+func newToken() ([]byte, error) {
+	token := make([]byte, tokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// sessionToken returns the synchronizer token bound to the session ID, derived with the secret.
+func sessionToken(secret []byte, sessionID string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(sessionID))
+	return mac.Sum(nil)
+}
+
+// mask XORs the token with a one-time pad prepended to the result, so that the token rendered in
+// compressed responses changes on every request, which defeats BREACH-style attacks.
+func mask(token []byte) string {
+	pad := make([]byte, len(token))
+	if _, err := rand.Read(pad); err != nil {
+		return ""
+	}
+
+	masked := make([]byte, 2*len(token))
+	copy(masked, pad)
+	for i := range token {
+		masked[len(token)+i] = pad[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// unmask decodes a submitted token, which is either masked or sent as is.
+func unmask(submitted string) []byte {
+	decoded, err := base64.RawURLEncoding.DecodeString(submitted)
+	if err != nil {
+		return nil
+	}
+
+	switch len(decoded) {
+	case tokenLength:
+		return decoded
+	case 2 * tokenLength:
+		token := make([]byte, tokenLength)
+		for i := range token {
+			token[i] = decoded[i] ^ decoded[tokenLength+i]
+		}
+		return token
+	default:
+		return nil
+	}
+}
+
+// validToken compares the submitted token to the expected one in constant time.
+func validToken(expected []byte, submitted string) bool {
+	token := unmask(submitted)
+	return len(expected) == tokenLength && subtle.ConstantTimeCompare(expected, token) == 1
+}
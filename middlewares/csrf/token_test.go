@@ -0,0 +1,52 @@
+package csrf
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidToken(t *testing.T) {
+	token, err := newToken()
+	require.NoError(t, err)
+	other, err := newToken()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		submitted string
+		want      bool
+	}{
+		{"Masked token", mask(token), true},
+		{"Raw token", base64.RawURLEncoding.EncodeToString(token), true},
+		{"Other token", mask(other), false},
+		{"Truncated token", mask(token)[:20], false},
+		{"Not base64", "not base64!", false},
+		{"Empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validToken(token, tt.submitted))
+		})
+	}
+}
+
+func TestMaskChangesEveryCall(t *testing.T) {
+	token, err := newToken()
+	require.NoError(t, err)
+
+	assert.NotEqual(t, mask(token), mask(token))
+	assert.Equal(t, token, unmask(mask(token)))
+}
+
+func TestSessionToken(t *testing.T) {
+	secret := []byte("secret")
+
+	assert.Equal(t, sessionToken(secret, "s1"), sessionToken(secret, "s1"))
+	assert.NotEqual(t, sessionToken(secret, "s1"), sessionToken(secret, "s2"))
+	assert.NotEqual(t, sessionToken(secret, "s1"), sessionToken([]byte("other"), "s1"))
+	assert.Len(t, sessionToken(secret, "s1"), tokenLength)
+}
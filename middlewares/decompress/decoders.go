@@ -0,0 +1,99 @@
+package decompress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported content codings.
+const (
+	Brotli  = "br"
+	Deflate = "deflate"
+	Gzip    = "gzip"
+	Zstd    = "zstd"
+)
+
+// newDecoder is a function that returns a reader decoding src.
+type newDecoder func(src io.Reader) (io.ReadCloser, error)
+
+// maxZstdWindow is the maximum zstd window size, which decoders allocate up front whatever the body size.
+// Streaming encoders declare their whole window, up to 8 MiB by default, even for small bodies.
+const maxZstdWindow = 8 << 20 // 8 MiB.
+
+// newDecoders returns the decoder constructors of the content codings,
+// with decoders bounded by maxDecompressed bytes, 0 meaning unlimited.
+func newDecoders(maxDecompressed int64) map[string]newDecoder {
+	return map[string]newDecoder{
+		Brotli: func(src io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(brotli.NewReader(src)), nil
+		},
+		Deflate: func(src io.Reader) (io.ReadCloser, error) {
+			// The "deflate" content coding is the zlib format (RFC 1950), not raw deflate.
+			return zlib.NewReader(src)
+		},
+		Gzip: func(src io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(src)
+		},
+		Zstd: newZstdPool(maxDecompressed).newDecoder,
+	}
+}
+
+// zstdPool holds reusable zstd decoders, which are expensive to allocate.
+type zstdPool struct {
+	sync.Pool
+}
+
+// newZstdPool returns a new zstdPool of decoders rejecting frames whose window exceeds maxZstdWindow,
+// or whose declared content size exceeds maxDecompressed.
+func newZstdPool(maxDecompressed int64) *zstdPool {
+	opts := []zstd.DOption{
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderLowmem(true),
+		zstd.WithDecoderMaxWindow(maxZstdWindow),
+	}
+	// Decoders reject windows over their maximum memory, which therefore can't be lower.
+	if maxDecompressed > 0 {
+		maxMemory := uint64(maxDecompressed)
+		if maxMemory < maxZstdWindow {
+			maxMemory = maxZstdWindow
+		}
+		opts = append(opts, zstd.WithDecoderMaxMemory(maxMemory))
+	}
+
+	return &zstdPool{Pool: sync.Pool{New: func() any {
+		d, _ := zstd.NewReader(nil, opts...)
+		return d
+	}}}
+}
+
+// newDecoder returns a pooled zstd decoder reading from src.
+func (p *zstdPool) newDecoder(src io.Reader) (io.ReadCloser, error) {
+	d := p.Get().(*zstd.Decoder)
+	if err := d.Reset(src); err != nil {
+		p.Put(d)
+		return nil, err
+	}
+	return &zstdDecoder{Decoder: d, pool: p}, nil
+}
+
+// zstdDecoder returns its decoder to the pool when closed.
+type zstdDecoder struct {
+	*zstd.Decoder
+	pool *zstdPool
+}
+
+// Close implements io.Closer.
+func (d *zstdDecoder) Close() error {
+	if d.Decoder == nil {
+		return nil
+	}
+	_ = d.Decoder.Reset(nil)
+	d.pool.Put(d.Decoder)
+	d.Decoder = nil
+	return nil
+}
@@ -0,0 +1,124 @@
+package decompress
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/bodylimit"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// Config is a struct that holds configuration options for the request decompression middleware.
+type Config struct {
+	Encodings       []string // Supported request content codings.
+	MaxDecompressed int64    // Maximum decompressed request body size in bytes, 0 means unlimited.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		Encodings:       []string{Gzip, Deflate, Brotli, Zstd},
+		MaxDecompressed: 10 << 20, // 10 MiB.
+	}
+}
+
+// Middleware is the request decompression middleware function that takes a Config struct and returns the middleware.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	decoders := newDecoders(config.MaxDecompressed)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encodings := parseContentEncoding(r.Header.Values("Content-Encoding"))
+			if len(encodings) == 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for _, encoding := range encodings {
+				if !slices.Contains(config.Encodings, encoding) {
+					slog.Error("request content encoding not supported", slog.String("encoding", encoding))
+					w.Header().Set("Accept-Encoding", strings.Join(config.Encodings, ", "))
+					problem.Error(w, http.StatusUnsupportedMediaType, "unsupported content encoding "+encoding)
+					return
+				}
+			}
+
+			body, err := decode(r.Body, encodings, decoders)
+			if err != nil {
+				slog.Error("request body decoding failed", slog.String("error", err.Error()))
+				problem.Error(w, http.StatusBadRequest, "malformed "+strings.Join(encodings, ", ")+" request body")
+				return
+			}
+			defer body.Close()
+
+			// The handler sees the decoded representation.
+			r.Body = body
+			r.ContentLength = -1
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+
+			bodylimit.ServeLimited(next, w, r, config.MaxDecompressed)
+		})
+	}
+}
+
+// parseContentEncoding returns the content codings listed in the Content-Encoding header values, ignoring identity.
+func parseContentEncoding(values []string) []string {
+	var encodings []string
+
+	for _, value := range values {
+		for _, encoding := range strings.Split(value, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if encoding == "x-gzip" {
+				encoding = Gzip
+			}
+			if encoding != "" && encoding != "identity" {
+				encodings = append(encodings, encoding)
+			}
+		}
+	}
+
+	return encodings
+}
+
+// decode returns a reader undoing the content codings, which were applied in the listed order.
+func decode(src io.ReadCloser, encodings []string, decoders map[string]newDecoder) (io.ReadCloser, error) {
+	body := &decodedBody{src: src}
+
+	var r io.Reader = src
+	for i := len(encodings) - 1; i >= 0; i-- {
+		dec, err := decoders[encodings[i]](r)
+		if err != nil {
+			_ = body.Close()
+			return nil, err
+		}
+		body.decoders = append(body.decoders, dec)
+		r = dec
+	}
+
+	body.Reader = r
+	return body, nil
+}
+
+// decodedBody closes its decoders along with the original request body.
+type decodedBody struct {
+	io.Reader
+	src      io.Closer
+	decoders []io.ReadCloser
+}
+
+// Close implements io.Closer.
+func (b *decodedBody) Close() error {
+	var errs []error
+	for _, dec := range b.decoders {
+		errs = append(errs, dec.Close())
+	}
+	b.decoders = nil
+	errs = append(errs, b.src.Close())
+	return errors.Join(errs...)
+}
@@ -0,0 +1,167 @@
+package decompress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+// encode compresses data with the given content coding.
+func encode(t *testing.T, encoding string, data string) []byte {
+	var (
+		buf = new(bytes.Buffer)
+		w   io.WriteCloser
+	)
+
+	switch encoding {
+	case Gzip:
+		w = gzip.NewWriter(buf)
+	case Brotli:
+		w = brotli.NewWriter(buf)
+	case Zstd:
+		enc, err := zstd.NewWriter(buf)
+		assert.NoError(t, err)
+		w = enc
+	default:
+		return []byte(data)
+	}
+
+	_, err := io.WriteString(w, data)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestParseContentEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{"No header", nil, nil},
+		{"Identity only", []string{"identity"}, nil},
+		{"Single encoding", []string{"gzip"}, []string{Gzip}},
+		{"Multiple encodings", []string{"gzip, br"}, []string{Gzip, Brotli}},
+		{"Multiple headers", []string{"gzip", "ZSTD"}, []string{Gzip, Zstd}},
+		{"Legacy alias", []string{"x-gzip"}, []string{Gzip}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseContentEncoding(tt.values))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	config := NewConfig()
+	config.MaxDecompressed = 1000
+
+	var (
+		payload = `{"message":"hello"}`
+		bomb    = strings.Repeat("0", 10000)
+	)
+
+	tests := []struct {
+		name            string
+		contentEncoding string
+		body            []byte
+		wantStatus      int
+		wantBody        string
+	}{
+		{"Not encoded", "", []byte(payload), http.StatusOK, payload},
+		{"Gzip", Gzip, encode(t, Gzip, payload), http.StatusOK, payload},
+		{"Brotli", Brotli, encode(t, Brotli, payload), http.StatusOK, payload},
+		{"Zstd", Zstd, encode(t, Zstd, payload), http.StatusOK, payload},
+		{"Stacked encodings", "gzip, zstd", encode(t, Zstd, string(encode(t, Gzip, payload))), http.StatusOK, payload},
+		{"Unsupported encoding", "compress", []byte(payload), http.StatusUnsupportedMediaType, ""},
+		{"Malformed body", Gzip, []byte(payload), http.StatusBadRequest, ""},
+		{"Decompressed size over limit", Gzip, encode(t, Gzip, bomb), http.StatusRequestEntityTooLarge, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Empty(t, r.Header.Get("Content-Encoding"))
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				w.Write(body)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.wantBody, rr.Body.String())
+			}
+			if tt.wantStatus == http.StatusUnsupportedMediaType {
+				assert.Equal(t, "gzip, deflate, br, zstd", rr.Header().Get("Accept-Encoding"))
+			}
+		})
+	}
+}
+
+// zstdFrame returns a zstd frame declaring a window of 2^windowLog bytes and holding data as a raw block.
+func zstdFrame(windowLog int, data string) []byte {
+	frame := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, byte(windowLog-10) << 3}
+	header := len(data)<<3 | 1 // Last raw block.
+	frame = append(frame, byte(header), byte(header>>8), byte(header>>16))
+	return append(frame, data...)
+}
+
+func TestMiddlewareZstdWindow(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxDecompressed int64
+		windowLog       int
+		wantStatus      int
+	}{
+		{"Window within cap", 1000, 23, http.StatusOK},
+		{"Window over cap", 1000, 24, http.StatusBadRequest},
+		{"Window over cap without limit", 0, 24, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.MaxDecompressed = tt.maxDecompressed
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				w.Write(body)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(zstdFrame(tt.windowLog, "hello")))
+			req.Header.Set("Content-Encoding", Zstd)
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "hello", rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package forwarded
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+// Forwarding headers carrying the client address.
+const (
+	HeaderForwarded     = "Forwarded"
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXRealIP       = "X-Real-IP"
+)
+
+// GoogleFrontEnds are the source ranges of Google Cloud load balancers and health checks.
+// Behind an external Application Load Balancer, its forwarding rule IP address must also be trusted,
+// as the load balancer appends it to X-Forwarded-For.
+var GoogleFrontEnds = []string{"35.191.0.0/16", "130.211.0.0/22"}
+
+// Config is a struct that holds configuration options for the forwarded headers middleware.
+type Config struct {
+	TrustedProxies []string // CIDRs or IP addresses of the proxies whose forwarding headers are trusted.
+	Headers        []string // Headers carrying the client address, by order of preference.
+}
+
+// NewConfig creates a new Config struct with default values.
+// Without trusted proxies, forwarding headers are ignored.
+func NewConfig() *Config {
+	return &Config{
+		TrustedProxies: []string{},
+		Headers:        []string{HeaderForwarded, HeaderXForwardedFor, HeaderXRealIP},
+	}
+}
+
+type clientIPKey struct{}
+
+// ClientIP returns the client IP address resolved by the middleware.
+func ClientIP(ctx context.Context) (netip.Addr, bool) {
+	addr, ok := ctx.Value(clientIPKey{}).(netip.Addr)
+	return addr, ok
+}
+
+// ClientAddress returns the client IP address of the request, as resolved by the middleware,
+// or else the host of the remote address.
+func ClientAddress(r *http.Request) string {
+	if addr, ok := ClientIP(r.Context()); ok {
+		return addr.String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Middleware is the forwarded headers middleware function that takes a Config struct and returns the middleware.
+// When the request comes from a trusted proxy, the client IP address is the rightmost untrusted hop of the
+// forwarding header, available with ClientIP, and the URL scheme and host are set to the forwarded ones.
+// The remote address of the request is left untouched.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	trusted := parsePrefixes(config.TrustedProxies)
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range trusted {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			remote, ok := parseNode(r.RemoteAddr)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client, proto, host := remote, "", ""
+			if isTrusted(remote) {
+				client, proto, host = resolve(r, config.Headers, isTrusted, remote)
+				if proto == "" {
+					proto = strings.ToLower(lastValue(r.Header.Values("X-Forwarded-Proto")))
+				}
+				if host == "" {
+					host = lastValue(r.Header.Values("X-Forwarded-Host"))
+				}
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), clientIPKey{}, client))
+			if proto == "http" || proto == "https" {
+				u := *r.URL
+				u.Scheme = proto
+				r.URL = &u
+			}
+			if validHost(host) {
+				r.Host = host
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// resolve returns the client address of a request from a trusted proxy, with the forwarded protocol and host
+// when carried by the Forwarded header. The first header present is used.
+func resolve(r *http.Request, headers []string, isTrusted func(netip.Addr) bool, remote netip.Addr) (netip.Addr, string, string) {
+	for _, header := range headers {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		switch http.CanonicalHeaderKey(header) {
+		case http.CanonicalHeaderKey(HeaderForwarded):
+			elements := parseForwarded(values)
+			nodes := make([]string, len(elements))
+			for i, e := range elements {
+				nodes[i] = e.forNode
+			}
+			if client, i, ok := rightmostUntrusted(nodes, isTrusted); ok {
+				return client, elements[i].proto, elements[i].host
+			}
+		default:
+			if client, _, ok := rightmostUntrusted(splitList(values), isTrusted); ok {
+				return client, "", ""
+			}
+		}
+		return remote, "", ""
+	}
+	return remote, "", ""
+}
+
+// rightmostUntrusted walks the hops from right to left and returns the first untrusted address with its index.
+// When every hop is trusted, or an invalid hop stops the walk, the last trusted hop is returned.
+func rightmostUntrusted(nodes []string, isTrusted func(netip.Addr) bool) (netip.Addr, int, bool) {
+	var (
+		last  netip.Addr
+		index = -1
+	)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		addr, ok := parseNode(nodes[i])
+		if !ok {
+			break
+		}
+		last, index = addr, i
+		if !isTrusted(addr) {
+			break
+		}
+	}
+	return last, index, index >= 0
+}
+
+// lastValue returns the last item of comma-separated header values, which was set by the nearest proxy.
+func lastValue(values []string) string {
+	items := splitList(values)
+	if len(items) == 0 {
+		return ""
+	}
+	return items[len(items)-1]
+}
+
+// parsePrefixes parses CIDRs and IP addresses, skipping invalid entries so that they are never trusted.
+func parsePrefixes(cidrs []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(cidr); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		slog.Error("invalid trusted proxy ignored", slog.String("cidr", cidr))
+	}
+	return prefixes
+}
@@ -0,0 +1,66 @@
+package forwarded
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	config := NewConfig()
+	config.TrustedProxies = append([]string{"10.0.0.0/8", "203.0.113.7", "not-a-cidr"}, GoogleFrontEnds...)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		wantIP     string
+		wantScheme string
+		wantHost   string
+	}{
+		{"Direct client", "198.51.100.1:1234", nil, "198.51.100.1", "", "example.com"},
+		{"Untrusted proxy", "198.51.100.1:1234", map[string]string{"X-Forwarded-For": "192.0.2.1", "X-Forwarded-Proto": "https"}, "198.51.100.1", "", "example.com"},
+		{"Trusted proxy", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "192.0.2.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"}, "192.0.2.1", "https", "api.example.com"},
+		{"Spoofed hops", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 192.0.2.1, 10.0.0.2"}, "192.0.2.1", "", "example.com"},
+		{"Google load balancer", "35.191.1.1:1234", map[string]string{"X-Forwarded-For": "192.0.2.1, 203.0.113.7"}, "192.0.2.1", "", "example.com"},
+		{"Only trusted hops", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3", "", "example.com"},
+		{"Invalid hop", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "192.0.2.1, garbage"}, "10.0.0.1", "", "example.com"},
+		{"Forwarded header", "10.0.0.1:1234", map[string]string{"Forwarded": `for=1.1.1.1, for="[2001:db8::1]:80";proto=https;host=api.example.com`, "X-Forwarded-For": "192.0.2.9"}, "2001:db8::1", "https", "api.example.com"},
+		{"X-Real-IP", "10.0.0.1:1234", map[string]string{"X-Real-IP": "192.0.2.1"}, "192.0.2.1", "", "example.com"},
+		{"Invalid host", "10.0.0.1:1234", map[string]string{"X-Forwarded-Host": "evil.example/path"}, "10.0.0.1", "", "example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotIP, gotScheme, gotHost string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotIP = ClientAddress(r)
+				gotScheme = r.URL.Scheme
+				gotHost = r.Host
+				assert.Equal(t, tt.remoteAddr, r.RemoteAddr)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantIP, gotIP)
+			assert.Equal(t, tt.wantScheme, gotScheme)
+			assert.Equal(t, tt.wantHost, gotHost)
+		})
+	}
+}
+
+func TestClientAddressWithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	assert.Equal(t, "192.0.2.1", ClientAddress(req))
+}
@@ -0,0 +1,98 @@
+package forwarded
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// element is a forwarded-element of the RFC 7239 Forwarded header.
+type element struct {
+	forNode string
+	proto   string
+	host    string
+}
+
+// parseForwarded parses the RFC 7239 Forwarded header values into their elements, in order.
+func parseForwarded(values []string) []element {
+	var elements []element
+	for _, value := range values {
+		for _, raw := range splitQuoted(value, ',') {
+			var e element
+			for _, pair := range splitQuoted(raw, ';') {
+				name, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				val = strings.Trim(val, `"`)
+				switch strings.ToLower(name) {
+				case "for":
+					e.forNode = val
+				case "proto":
+					e.proto = strings.ToLower(val)
+				case "host":
+					e.host = val
+				}
+			}
+			elements = append(elements, e)
+		}
+	}
+	return elements
+}
+
+// splitQuoted splits s around sep, ignoring separators within quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var (
+		parts  []string
+		start  int
+		quoted bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// splitList splits comma-separated header values, such as X-Forwarded-For, in order.
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// parseNode parses an address with an optional port, such as "192.0.2.1", "192.0.2.1:4711",
+// "2001:db8::1" or "[2001:db8::1]:4711". Obfuscated and "unknown" nodes are invalid.
+func parseNode(node string) (netip.Addr, bool) {
+	node = strings.TrimSpace(node)
+	if addr, err := netip.ParseAddr(strings.Trim(node, "[]")); err == nil {
+		return addr.Unmap(), true
+	}
+	host, _, err := net.SplitHostPort(node)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// validHost checks if the forwarded host is a plain host with an optional port, so it is safe to use.
+func validHost(host string) bool {
+	return host != "" && !strings.ContainsAny(host, "/\\?#@ \t\"")
+}
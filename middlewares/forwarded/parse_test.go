@@ -0,0 +1,49 @@
+package forwarded
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseForwarded(t *testing.T) {
+	values := []string{
+		`for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"`,
+		`For="198.51.100.17;x";Proto=HTTPS;host=example.com`,
+	}
+
+	want := []element{
+		{forNode: "192.0.2.60", proto: "http"},
+		{forNode: "[2001:db8:cafe::17]:4711"},
+		{forNode: "198.51.100.17;x", proto: "https", host: "example.com"},
+	}
+	assert.Equal(t, want, parseForwarded(values))
+}
+
+func TestParseNode(t *testing.T) {
+	tests := []struct {
+		node   string
+		want   string
+		wantOK bool
+	}{
+		{"192.0.2.1", "192.0.2.1", true},
+		{" 192.0.2.1:4711", "192.0.2.1", true},
+		{"2001:db8::1", "2001:db8::1", true},
+		{"[2001:db8::1]:4711", "2001:db8::1", true},
+		{"::ffff:192.0.2.1", "192.0.2.1", true},
+		{"unknown", "", false},
+		{"_hidden", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.node, func(t *testing.T) {
+			addr, ok := parseNode(tt.node)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, netip.MustParseAddr(tt.want), addr)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// Header is the request header carrying the idempotency key.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is the response header marking replayed responses.
+const ReplayedHeader = "Idempotent-Replayed"
+
+// Config is a struct that holds configuration options for the idempotency middleware.
+type Config struct {
+	Store            Store                      // Storage backend, nil means a MemoryStore.
+	Methods          []string                   // Methods supporting idempotency keys.
+	Required         bool                       // Flag to reject requests with these methods but no key.
+	TTL              time.Duration              // Duration records are kept, retries after that are new requests.
+	LockTTL          time.Duration              // Duration keys stay locked while processed, so that crashes don't lock them for the TTL.
+	WaitTimeout      time.Duration              // Duration a duplicate waits for the first request, 0 rejects it right away.
+	PollInterval     time.Duration              // Interval between checks of the first request while waiting.
+	MaxBodySize      int                        // Maximum size of stored response bodies, larger responses aren't stored.
+	ScopeFunc        func(*http.Request) string // Function returning the scope of keys, so clients can't replay each other.
+	ExcludedPrefixes []string                   // Path prefixes that don't support idempotency keys.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		Store:            nil,
+		Methods:          []string{http.MethodPost, http.MethodPatch},
+		Required:         false,
+		TTL:              24 * time.Hour,
+		LockTTL:          time.Minute,
+		WaitTimeout:      0,
+		PollInterval:     50 * time.Millisecond,
+		MaxBodySize:      1 << 20,
+		ScopeFunc:        ScopeByIdentity,
+		ExcludedPrefixes: []string{},
+	}
+}
+
+// ScopeByIdentity scopes keys to the authenticated caller, if any.
+func ScopeByIdentity(r *http.Request) string {
+	if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+		return identity.Method + ":" + identity.Subject
+	}
+	return ""
+}
+
+// Middleware is the idempotency middleware function that takes a Config struct and returns the middleware.
+// It implements the IETF Idempotency-Key header: the first response to a key is stored and replayed on retries,
+// retries with a different request are rejected with 422, and concurrent duplicates get a 409.
+// Server errors aren't stored so that the request can be retried.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	store := config.Store
+	if store == nil {
+		store = NewMemoryStore(time.Minute)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(config.Methods, r.Method) || common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := parseKey(r.Header.Get(Header))
+			if key == "" {
+				if config.Required {
+					problem.Error(w, http.StatusBadRequest, "missing "+Header+" header")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fingerprint, err := fingerprintRequest(r)
+			if err != nil {
+				problem.Error(w, http.StatusBadRequest, "failed to read request body")
+				return
+			}
+
+			ctx := r.Context()
+			storeKey := storageKey(config.ScopeFunc(r), key)
+			existing, err := store.Begin(ctx, storeKey, fingerprint, config.LockTTL)
+			if err != nil {
+				slog.Error("failed to begin idempotent request", slog.String("error", err.Error()))
+				problem.Error(w, http.StatusServiceUnavailable, "idempotency storage unavailable")
+				return
+			}
+
+			if existing != nil {
+				if existing.Fingerprint != fingerprint {
+					problem.Error(w, http.StatusUnprocessableEntity, Header+" reused with a different request")
+					return
+				}
+				if !existing.Completed && config.WaitTimeout > 0 {
+					existing = wait(ctx, store, storeKey, config)
+				}
+				if existing == nil || !existing.Completed {
+					problem.Error(w, http.StatusConflict, "a request with the same "+Header+" is being processed")
+					return
+				}
+				replay(w, existing)
+				return
+			}
+
+			iw := &idempotencyWriter{ResponseWriter: w, initialHeader: w.Header().Clone(), maxBodySize: config.MaxBodySize}
+			completed := false
+			defer func() {
+				// Release the key when the handler panics or fails, so that the request can be retried.
+				if !completed {
+					if err := store.Release(context.Background(), storeKey); err != nil {
+						slog.Error("failed to release idempotency key", slog.String("error", err.Error()))
+					}
+				}
+			}()
+
+			next.ServeHTTP(iw, r)
+
+			if iw.status == 0 {
+				iw.WriteHeader(http.StatusOK)
+			}
+			if iw.status >= http.StatusInternalServerError {
+				return
+			}
+			// A truncated record would be replayed as a corrupt response, so the key is released instead.
+			if iw.tooLarge {
+				slog.Warn("idempotent response too large to store", slog.String("path", r.URL.Path))
+				return
+			}
+
+			record := &Record{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				Status:      iw.status,
+				Header:      iw.header,
+				Body:        iw.body.Bytes(),
+			}
+			// The record is stored even if the client went away, as that's when it retries.
+			if err := store.Complete(common.Detach(ctx), storeKey, record, config.TTL); err != nil {
+				slog.Error("failed to store idempotent response", slog.String("error", err.Error()))
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// parseKey returns the key of the header, a structured field string which may be sent unquoted by clients.
+func parseKey(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+	return value
+}
+
+// storageKey returns the key records are stored under.
+func storageKey(scope, key string) string {
+	sum := sha256.Sum256([]byte(scope + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprintRequest returns a hash of the request method, URI and body, restoring the body for the handler.
+func fingerprintRequest(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\x00"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// wait polls the record of the key until it is completed, released or the wait times out.
+func wait(ctx context.Context, store Store, key string, config *Config) *Record {
+	ctx, cancel := context.WithTimeout(ctx, config.WaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			record, err := store.Get(ctx, key)
+			if err != nil {
+				return nil
+			}
+			if record.Completed {
+				return record
+			}
+		}
+	}
+}
+
+// replay writes the stored response, keeping the headers already set for the current request.
+func replay(w http.ResponseWriter, record *Record) {
+	header := w.Header()
+	common.MergeHeaders(header, record.Header)
+	header.Set(ReplayedHeader, "true")
+	w.WriteHeader(record.Status)
+	_, _ = w.Write(record.Body)
+}
+
+// idempotencyWriter is a http.ResponseWriter recording the response while writing it.
+type idempotencyWriter struct {
+	http.ResponseWriter
+	initialHeader http.Header
+	maxBodySize   int
+	status        int
+	header        http.Header
+	body          bytes.Buffer
+	tooLarge      bool
+}
+
+// WriteHeader implements http.ResponseWriter and records the status code and the headers set by the handler,
+// as those set by outer middlewares, such as the request ID, belong to the current request.
+// Cookies aren't recorded, as they may belong to another session by the time the response is replayed.
+func (w *idempotencyWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+		w.header = common.AddedHeaders(w.initialHeader, w.Header())
+		w.header.Del("Set-Cookie")
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter and records the body, until it exceeds the maximum size.
+func (w *idempotencyWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.tooLarge && w.body.Len()+len(b) > w.maxBodySize {
+		w.tooLarge = true
+		w.body = bytes.Buffer{}
+	}
+	if !w.tooLarge {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *idempotencyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,229 @@
+package idempotency
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2n3g5c9/go-http/middlewares/auth"
+	"github.com/2n3g5c9/go-http/middlewares/requestid"
+)
+
+// orderHandler creates an order per call, failing when the body asks to.
+func orderHandler(calls *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "fail" {
+			calls.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		n := calls.Add(1)
+		w.Header().Set("Location", "/orders/"+strconv.Itoa(int(n)))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("order " + strconv.Itoa(int(n))))
+	})
+}
+
+func post(handler http.Handler, key, body string, identity *auth.Identity) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	if identity != nil {
+		req = auth.WithIdentity(req, identity)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	handler := Middleware(NewConfig())(orderHandler(&calls))
+
+	first := post(handler, `"k1"`, "item=1", nil)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, "order 1", first.Body.String())
+
+	t.Run("Replay", func(t *testing.T) {
+		rr := post(handler, "k1", "item=1", nil)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "order 1", rr.Body.String())
+		assert.Equal(t, "/orders/1", rr.Header().Get("Location"))
+		assert.Equal(t, "true", rr.Header().Get(ReplayedHeader))
+	})
+
+	t.Run("Different request", func(t *testing.T) {
+		rr := post(handler, "k1", "item=2", nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("Other caller", func(t *testing.T) {
+		rr := post(handler, "k1", "item=1", &auth.Identity{Subject: "alice", Method: "jwt"})
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "order 2", rr.Body.String())
+	})
+
+	t.Run("No key", func(t *testing.T) {
+		rr := post(handler, "", "item=1", nil)
+		assert.Equal(t, "order 3", rr.Body.String())
+	})
+
+	t.Run("Server errors can be retried", func(t *testing.T) {
+		assert.Equal(t, http.StatusInternalServerError, post(handler, "k2", "fail", nil).Code)
+		assert.Equal(t, http.StatusInternalServerError, post(handler, "k2", "fail", nil).Code)
+		assert.Equal(t, int32(5), calls.Load())
+	})
+}
+
+func TestMiddlewareRequired(t *testing.T) {
+	config := NewConfig()
+	config.Required = true
+	handler := Middleware(config)(orderHandler(new(atomic.Int32)))
+
+	assert.Equal(t, http.StatusBadRequest, post(handler, "", "item=1", nil).Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+}
+
+func TestMiddlewareConcurrentDuplicates(t *testing.T) {
+	tests := []struct {
+		name        string
+		waitTimeout time.Duration
+		wantStatus  int
+	}{
+		{"Conflict", 0, http.StatusConflict},
+		{"Wait", time.Second, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.WaitTimeout = tt.waitTimeout
+			config.PollInterval = 5 * time.Millisecond
+
+			var (
+				calls   atomic.Int32
+				started = make(chan struct{})
+				release = make(chan struct{})
+			)
+			handler := Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				close(started)
+				<-release
+				w.WriteHeader(http.StatusCreated)
+			}))
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				post(handler, "k1", "item=1", nil)
+			}()
+			<-started
+
+			if tt.waitTimeout > 0 {
+				time.AfterFunc(20*time.Millisecond, func() { close(release) })
+			}
+			rr := post(handler, "k1", "item=1", nil)
+			if tt.waitTimeout == 0 {
+				close(release)
+			}
+			wg.Wait()
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, int32(1), calls.Load())
+		})
+	}
+}
+
+// contextStore is a Store failing with the context error, like network stores, and recording lock TTLs.
+type contextStore struct {
+	Store
+	lockTTL time.Duration
+}
+
+func (s *contextStore) Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	s.lockTTL = ttl
+	return s.Store.Begin(ctx, key, fingerprint, ttl)
+}
+
+func (s *contextStore) Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Store.Complete(ctx, key, record, ttl)
+}
+
+func TestMiddlewareClientGone(t *testing.T) {
+	store := &contextStore{Store: NewMemoryStore(time.Minute)}
+	config := NewConfig()
+	config.Store = store
+
+	var calls atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// The client disconnects while the order is being created.
+		cancel()
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("item=1")).WithContext(ctx)
+	req.Header.Set(Header, "k1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// The retry is replayed instead of creating the order again.
+	rr := post(handler, "k1", "item=1", nil)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get(ReplayedHeader))
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, config.LockTTL, store.lockTTL)
+}
+
+func TestMiddlewareOuterHeaders(t *testing.T) {
+	var calls atomic.Int32
+	handler := requestid.Middleware(requestid.NewConfig())(Middleware(NewConfig())(orderHandler(&calls)))
+
+	ids := make([]string, 2)
+	for i := range ids {
+		rr := post(handler, "k1", "item=1", nil)
+		require.Equal(t, "order 1", rr.Body.String())
+		ids[i] = rr.Header().Get(requestid.Header)
+		require.NotEmpty(t, ids[i])
+	}
+
+	// The request ID set outside the middleware isn't replayed.
+	assert.Equal(t, int32(1), calls.Load())
+	assert.NotEqual(t, ids[0], ids[1])
+}
+
+func TestMiddlewareLargeResponse(t *testing.T) {
+	config := NewConfig()
+	config.MaxBodySize = 4
+
+	var calls atomic.Int32
+	handler := Middleware(config)(orderHandler(&calls))
+
+	// Responses over the maximum size aren't stored, and the key is released for retries.
+	for i := 1; i <= 2; i++ {
+		rr := post(handler, "k1", "item=1", nil)
+		assert.Equal(t, "order "+strconv.Itoa(i), rr.Body.String())
+		assert.Empty(t, rr.Header().Get(ReplayedHeader))
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
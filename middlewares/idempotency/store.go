@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by stores when no record exists for a key.
+var ErrNotFound = errors.New("idempotency record not found")
+
+// Record is the state of an idempotency key: in progress until the first response completes, which is then replayed.
+type Record struct {
+	Fingerprint string      `json:"fingerprint"`
+	Completed   bool        `json:"completed"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Store is an interface for idempotency record storage backends.
+type Store interface {
+	// Begin atomically creates an in-progress record for the key unless one exists, which is then returned.
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (existing *Record, err error)
+	// Get returns the record of the key, or ErrNotFound.
+	Get(ctx context.Context, key string) (*Record, error)
+	// Complete stores the completed record of the key.
+	Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error
+	// Release removes the record of the key, so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryStore is a Store keeping records in process memory, keys are only idempotent per instance.
+type MemoryStore struct {
+	mu            sync.Mutex
+	entries       map[string]memoryEntry
+	sweepInterval time.Duration
+	nextSweep     time.Time
+}
+
+// memoryEntry is a record stored by MemoryStore.
+type memoryEntry struct {
+	record    *Record
+	expiresAt time.Time
+}
+
+// NewMemoryStore returns a new MemoryStore sweeping expired records every sweepInterval.
+func NewMemoryStore(sweepInterval time.Duration) *MemoryStore {
+	return &MemoryStore{
+		entries:       make(map[string]memoryEntry),
+		sweepInterval: sweepInterval,
+	}
+}
+
+// Begin implements Store.
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for k, e := range s.entries {
+			if now.After(e.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(s.sweepInterval)
+	}
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+		return e.record, nil
+	}
+	s.entries[key] = memoryEntry{record: &Record{Fingerprint: fingerprint}, expiresAt: now.Add(ttl)}
+	return nil, nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, key string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, ErrNotFound
+	}
+	return e.record, nil
+}
+
+// Complete implements Store.
+func (s *MemoryStore) Complete(_ context.Context, key string, record *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryEntry{record: record, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release implements Store.
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
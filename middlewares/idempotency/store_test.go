@@ -0,0 +1,45 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	server := miniredis.RunT(t)
+	stores := map[string]Store{
+		"Memory": NewMemoryStore(time.Minute),
+		"Redis":  NewRedisStore(redis.NewClient(&redis.Options{Addr: server.Addr()}), "idempotency:"),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			existing, err := store.Begin(ctx, "k1", "fp", time.Hour)
+			require.NoError(t, err)
+			assert.Nil(t, existing)
+
+			existing, err = store.Begin(ctx, "k1", "other", time.Hour)
+			require.NoError(t, err)
+			assert.Equal(t, &Record{Fingerprint: "fp"}, existing)
+
+			record := &Record{Fingerprint: "fp", Completed: true, Status: http.StatusCreated, Header: http.Header{"Location": {"/orders/1"}}, Body: []byte("{}")}
+			require.NoError(t, store.Complete(ctx, "k1", record, time.Hour))
+			got, err := store.Get(ctx, "k1")
+			require.NoError(t, err)
+			assert.Equal(t, record, got)
+
+			require.NoError(t, store.Release(ctx, "k1"))
+			_, err = store.Get(ctx, "k1")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package ipfilter
+
+import (
+	"bytes"
+	"fmt"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// FileSet is a Matcher reading CIDRs from a file, one per line, reloaded when it changes.
+type FileSet struct {
+	path          string
+	checkInterval time.Duration
+
+	mu        sync.RWMutex
+	set       *Set
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewFileSet returns a new FileSet reading the given file, checked for changes every checkInterval.
+func NewFileSet(path string, checkInterval time.Duration) (*FileSet, error) {
+	s := &FileSet{path: path, checkInterval: checkInterval}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Contains implements Matcher.
+func (s *FileSet) Contains(addr netip.Addr) bool {
+	s.mu.RLock()
+	stale := time.Since(s.checkedAt) > s.checkInterval
+	s.mu.RUnlock()
+
+	if stale {
+		// Keep using the previous set if the file became unreadable or invalid.
+		if err := s.reload(); err != nil {
+			slog.Error("failed to reload IP set", slog.String("path", s.path), slog.String("error", err.Error()))
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.set.Contains(addr)
+}
+
+// reload reads the file again if it was modified since the last read.
+func (s *FileSet) reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkedAt = time.Now()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	set, err := ParseSet(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("parse IP set file %s: %w", s.path, err)
+	}
+
+	s.set, s.modTime = set, info.ModTime()
+	return nil
+}
@@ -0,0 +1,36 @@
+package ipfilter
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+)
+
+// Attribute keys for the IP filter rule and denial reason.
+const (
+	ruleKey   = attribute.Key("ipfilter.rule")
+	reasonKey = attribute.Key("ipfilter.reason")
+)
+
+type Metrics struct {
+	deniedCounter metric.Int64Counter
+}
+
+// NewMetrics returns a new Metrics instance.
+func NewMetrics(meter *metric.Meter) *Metrics {
+	deniedCounter, _ := (*meter).Int64Counter(
+		"http_requests_ip_denied_total",
+		metric.WithDescription("Total number of HTTP requests denied by IP filtering."),
+	)
+
+	return &Metrics{
+		deniedCounter: deniedCounter,
+	}
+}
+
+// IncreaseDeniedCounter increases the denied request counter by 1.
+func (m *Metrics) IncreaseDeniedCounter(ctx context.Context, method, rule, reason string) {
+	m.deniedCounter.Add(ctx, 1, metric.WithAttributes(semconv.HTTPMethodKey.String(method), ruleKey.String(rule), reasonKey.String(reason)))
+}
@@ -0,0 +1,105 @@
+package ipfilter
+
+import (
+	"net/http"
+	"net/netip"
+	"reflect"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/forwarded"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// defaultRule is the name of the rule applied to paths without a route rule.
+const defaultRule = "default"
+
+// Denial reasons, used as metric attribute values.
+const (
+	reasonDenylisted     = "denylisted"
+	reasonNotAllowlisted = "not_allowlisted"
+)
+
+// Rule is a pair of allowlist and denylist. The denylist takes precedence, and a nil allowlist allows
+// every address that isn't denied.
+type Rule struct {
+	Allow Matcher // Addresses allowed, nil allows all.
+	Deny  Matcher // Addresses denied, nil denies none.
+}
+
+// evaluate returns the reason why the address is denied, or an empty string if it is allowed.
+// Invalid addresses only pass rules without allowlist.
+func (r *Rule) evaluate(addr netip.Addr) string {
+	if r.Deny != nil && r.Deny.Contains(addr) {
+		return reasonDenylisted
+	}
+	if r.Allow != nil && !r.Allow.Contains(addr) {
+		return reasonNotAllowlisted
+	}
+	return ""
+}
+
+// Config is a struct that holds configuration options for the IP filter middleware.
+type Config struct {
+	DefaultRule      *Rule           // Rule applied to paths without a route rule, nil allows all.
+	RouteRules       map[string]Rule // Rules per path prefix, overriding the default rule.
+	ExcludedPrefixes []string        // Path prefixes that are never filtered.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		DefaultRule:      nil,
+		RouteRules:       map[string]Rule{},
+		ExcludedPrefixes: []string{},
+	}
+}
+
+// ruleFor returns the rule name and rule that apply to the given path, nil meaning no filtering.
+func (c *Config) ruleFor(path string) (string, *Rule) {
+	if prefix, rule, ok := common.MatchPrefix(path, c.RouteRules); ok {
+		return prefix, &rule
+	}
+	return defaultRule, c.DefaultRule
+}
+
+// Middleware is the IP filter middleware function that takes a Config struct and returns the middleware.
+// It filters on the client IP address resolved by the forwarded middleware, or else the remote address.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	var (
+		pkgName = reflect.TypeOf(struct{}{}).PkgPath()
+		meter   = otel.GetMeterProvider().Meter(pkgName)
+		metrics = NewMetrics(&meter)
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			name, rule := config.ruleFor(r.URL.Path)
+			if rule == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := forwarded.ClientAddress(r)
+			addr, _ := netip.ParseAddr(client)
+			if reason := rule.evaluate(addr); reason != "" {
+				slog.Error("request from IP address denied",
+					slog.String("clientIp", client),
+					slog.String("rule", name),
+					slog.String("reason", reason))
+				metrics.IncreaseDeniedCounter(r.Context(), r.Method, name, reason)
+				problem.Error(w, http.StatusForbidden, "client IP address not allowed")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
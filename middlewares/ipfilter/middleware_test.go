@@ -0,0 +1,74 @@
+package ipfilter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	office, err := NewSet([]string{"192.0.2.0/24"})
+	require.NoError(t, err)
+	blocked, err := NewSet([]string{"198.51.100.0/24"})
+	require.NoError(t, err)
+
+	config := NewConfig()
+	config.DefaultRule = &Rule{Deny: blocked}
+	config.RouteRules = map[string]Rule{"/admin": {Allow: office, Deny: blocked}}
+	config.ExcludedPrefixes = []string{"/health"}
+
+	tests := []struct {
+		name       string
+		target     string
+		remoteAddr string
+		wantStatus int
+	}{
+		{"Allowed by default", "/", "203.0.113.1:1234", http.StatusOK},
+		{"Denylisted", "/", "198.51.100.1:1234", http.StatusForbidden},
+		{"Allowlisted route", "/admin/users", "192.0.2.1:1234", http.StatusOK},
+		{"Not allowlisted route", "/admin/users", "203.0.113.1:1234", http.StatusForbidden},
+		{"Invalid address on allowlisted route", "/admin/users", "garbage", http.StatusForbidden},
+		{"Excluded path", "/health", "198.51.100.1:1234", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.RemoteAddr = tt.remoteAddr
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
+
+func TestFileSetReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("192.0.2.0/24\n"), 0o600))
+
+	set, err := NewFileSet(path, 0)
+	require.NoError(t, err)
+	assert.True(t, set.Contains(netip.MustParseAddr("192.0.2.1")))
+
+	// Make sure the modification time changes on filesystems with coarse timestamps.
+	require.NoError(t, os.WriteFile(path, []byte("198.51.100.0/24\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now().Add(time.Second), time.Now().Add(time.Second)))
+	assert.False(t, set.Contains(netip.MustParseAddr("192.0.2.1")))
+	assert.True(t, set.Contains(netip.MustParseAddr("198.51.100.1")))
+
+	// An invalid file keeps the previous set.
+	require.NoError(t, os.WriteFile(path, []byte("not a cidr\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now().Add(2*time.Second), time.Now().Add(2*time.Second)))
+	assert.True(t, set.Contains(netip.MustParseAddr("198.51.100.1")))
+}
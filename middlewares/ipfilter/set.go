@@ -0,0 +1,144 @@
+package ipfilter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Matcher is an interface for sets of IP addresses.
+type Matcher interface {
+	// Contains checks if the address belongs to the set.
+	Contains(addr netip.Addr) bool
+}
+
+// ipRange is an inclusive range of IP addresses.
+type ipRange struct {
+	first, last netip.Addr
+}
+
+// Set is an immutable set of CIDRs, merged into sorted disjoint ranges so that lookups are
+// logarithmic in the number of ranges.
+type Set struct {
+	ranges []ipRange
+}
+
+// NewSet returns a new Set of the given CIDRs or IP addresses.
+func NewSet(cidrs []string) (*Set, error) {
+	ranges := make([]ipRange, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		r, err := parseRange(cidr)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return &Set{ranges: merge(ranges)}, nil
+}
+
+// ParseSet returns a new Set of the CIDRs or IP addresses read from r, one per line.
+// Empty lines and comments starting with "#" are ignored.
+func ParseSet(r io.Reader) (*Set, error) {
+	var (
+		cidrs   []string
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			cidrs = append(cidrs, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewSet(cidrs)
+}
+
+// Contains implements Matcher.
+func (s *Set) Contains(addr netip.Addr) bool {
+	if s == nil || !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+
+	// Find the last range starting at or before the address.
+	i := sort.Search(len(s.ranges), func(i int) bool {
+		return s.ranges[i].first.Compare(addr) > 0
+	})
+	return i > 0 && s.ranges[i-1].last.Compare(addr) >= 0
+}
+
+// Len returns the number of disjoint ranges of the set.
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.ranges)
+}
+
+// parseRange parses a CIDR or an IP address into a range.
+func parseRange(cidr string) (ipRange, error) {
+	cidr = strings.TrimSpace(cidr)
+	if addr, err := netip.ParseAddr(cidr); err == nil {
+		addr = addr.Unmap()
+		return ipRange{first: addr, last: addr}, nil
+	}
+
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return ipRange{}, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+	prefix = prefix.Masked()
+	return ipRange{first: prefix.Addr(), last: lastAddr(prefix)}, nil
+}
+
+// lastAddr returns the last address of the prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	var (
+		b    = prefix.Addr().As16()
+		bits = prefix.Bits()
+	)
+	if prefix.Addr().Is4() {
+		bits += 96
+	}
+	for i := bits; i < 128; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+
+	addr := netip.AddrFrom16(b)
+	if prefix.Addr().Is4() {
+		return addr.Unmap()
+	}
+	return addr
+}
+
+// merge sorts the ranges and merges the overlapping and adjacent ones.
+func merge(ranges []ipRange) []ipRange {
+	if len(ranges) == 0 {
+		return ranges
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].first.Compare(ranges[j].first) < 0
+	})
+
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		current := &merged[len(merged)-1]
+		if r.first.Compare(current.last) <= 0 || r.first == current.last.Next() {
+			if r.last.Compare(current.last) > 0 {
+				current.last = r.last
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
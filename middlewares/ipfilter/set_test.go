@@ -0,0 +1,58 @@
+package ipfilter
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetContains(t *testing.T) {
+	set, err := NewSet([]string{"10.0.0.0/8", "192.0.2.0/25", "192.0.2.128/25", "198.51.100.7", "2001:db8::/32", "::ffff:203.0.113.0/120"})
+	require.NoError(t, err)
+
+	// Adjacent ranges are merged.
+	assert.Equal(t, 5, set.Len())
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"10.0.0.0", true},
+		{"10.255.255.255", true},
+		{"11.0.0.0", false},
+		{"9.255.255.255", false},
+		{"192.0.2.200", true},
+		{"198.51.100.7", true},
+		{"198.51.100.8", false},
+		{"203.0.113.9", true},
+		{"::ffff:10.1.2.3", true},
+		{"2001:db8:1::1", true},
+		{"2001:db9::1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, set.Contains(netip.MustParseAddr(tt.addr)))
+		})
+	}
+
+	assert.False(t, set.Contains(netip.Addr{}))
+	assert.False(t, (*Set)(nil).Contains(netip.MustParseAddr("10.0.0.1")))
+}
+
+func TestNewSetInvalid(t *testing.T) {
+	_, err := NewSet([]string{"10.0.0.0/8", "10.0.0.0/33"})
+	assert.Error(t, err)
+}
+
+func TestParseSet(t *testing.T) {
+	set, err := ParseSet(strings.NewReader("# Office\n192.0.2.0/24 # VPN\n\n  198.51.100.1  \n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, set.Len())
+	assert.True(t, set.Contains(netip.MustParseAddr("192.0.2.10")))
+	assert.True(t, set.Contains(netip.MustParseAddr("198.51.100.1")))
+}
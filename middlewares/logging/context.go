@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/exp/slog"
+)
+
+type (
+	attrsKey  struct{}
+	holderKey struct{}
+)
+
+// attrsHolder collects the attributes added by inner handlers, which the access log is written with.
+type attrsHolder struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// withHolder returns a copy of ctx carrying a new attrsHolder, along with the holder.
+func withHolder(ctx context.Context) (context.Context, *attrsHolder) {
+	holder := &attrsHolder{}
+	return context.WithValue(ctx, holderKey{}, holder), holder
+}
+
+// add appends attributes to the holder.
+func (h *attrsHolder) add(attrs []slog.Attr) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.attrs = append(h.attrs, attrs...)
+}
+
+// args returns the attributes of the holder as logger arguments.
+func (h *attrsHolder) args() []any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	args := make([]any, len(h.attrs))
+	for i, attr := range h.attrs {
+		args[i] = attr
+	}
+	return args
+}
+
+// WithAttrs returns a copy of ctx carrying additional attributes for the request logger.
+// The attributes are also added to the access log of the request.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if holder, ok := ctx.Value(holderKey{}).(*attrsHolder); ok {
+		holder.add(attrs)
+	}
+
+	existing, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+// FromContext returns the default logger enriched with the attributes carried by ctx.
+func FromContext(ctx context.Context) *slog.Logger {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	if len(attrs) == 0 {
+		return slog.Default()
+	}
+
+	args := make([]any, len(attrs))
+	for i, attr := range attrs {
+		args[i] = attr
+	}
+	return slog.Default().With(args...)
+}
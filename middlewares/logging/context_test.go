@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func TestFromContext(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs [][]slog.Attr
+		want  []string
+	}{
+		{"No attributes", nil, nil},
+		{"Single call", [][]slog.Attr{{slog.String("subject", "alice")}}, []string{`"subject":"alice"`}},
+		{"Multiple calls", [][]slog.Attr{{slog.String("subject", "alice")}, {slog.String("operationId", "getUser")}}, []string{`"subject":"alice"`, `"operationId":"getUser"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			slog.SetDefault(slog.New(slog.NewJSONHandler(buf, nil)))
+
+			ctx := context.Background()
+			for _, attrs := range tt.attrs {
+				ctx = WithAttrs(ctx, attrs...)
+			}
+
+			FromContext(ctx).Info("handled")
+
+			for _, want := range tt.want {
+				assert.Contains(t, buf.String(), want)
+			}
+		})
+	}
+}
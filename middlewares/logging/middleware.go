@@ -6,6 +6,7 @@ import (
 	"golang.org/x/exp/slog"
 
 	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/forwarded"
 )
 
 var gitCommit string
@@ -13,7 +14,8 @@ var gitCommit string
 type MiddlewareOption func(*middlewareOptions)
 
 type middlewareOptions struct {
-	excludedPrefixes []string
+	excludedPrefixes    []string
+	redactedQueryParams []string
 }
 
 // WithExcludedPrefixes sets the excluded paths for the middleware.
@@ -23,7 +25,15 @@ func WithExcludedPrefixes(prefixes []string) MiddlewareOption {
 	}
 }
 
-// Middleware is a middleware that provides basic HTTP access logging.
+// WithRedactedQueryParams sets query parameters whose values are redacted from the URL, on top of
+// common.SensitiveQueryParams.
+func WithRedactedQueryParams(params []string) MiddlewareOption {
+	return func(options *middlewareOptions) {
+		options.redactedQueryParams = params
+	}
+}
+
+// Middleware is a middleware that provides basic HTTP access logging, once requests are handled.
 func Middleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
 	options := &middlewareOptions{}
 
@@ -37,11 +47,15 @@ func Middleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
 			return
 		}
 
-		slog.Info("request received",
+		// The request is logged once handled, with the attributes added by inner handlers such as the caller identity.
+		ctx, holder := withHolder(r.Context())
+		next.ServeHTTP(w, r.WithContext(ctx))
+
+		FromContext(r.Context()).With(holder.args()...).Info("request completed",
 			slog.String("method", r.Method),
-			slog.String("url", r.URL.String()),
+			slog.String("url", common.RedactedURL(r.URL, options.redactedQueryParams)),
 			slog.String("userAgent", r.UserAgent()),
+			slog.String("clientIp", forwarded.ClientAddress(r)),
 		)
-		next.ServeHTTP(w, r)
 	})
 }
@@ -2,6 +2,7 @@ package logging
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -45,3 +46,53 @@ func TestMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestMiddlewareRedaction(t *testing.T) {
+	tests := []struct {
+		name                string
+		target              string
+		redactedQueryParams []string
+		wantURL             string
+	}{
+		{"Default sensitive parameter", "/api?api_key=s3cr3t", nil, "/api?api_key=REDACTED"},
+		{"Configured parameter", "/api?client_secret=s3cr3t", []string{"client_secret"}, "/api?client_secret=REDACTED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			slog.SetDefault(slog.New(slog.NewJSONHandler(buf, nil)))
+
+			nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			middlewareHandler := Middleware(nextHandler, WithRedactedQueryParams(tt.redactedQueryParams))
+
+			middlewareHandler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.target, nil))
+
+			logged := buf.String()
+			assert.Contains(t, logged, tt.wantURL)
+			assert.NotContains(t, logged, "s3cr3t")
+		})
+	}
+}
+
+func TestMiddlewareInnerAttrs(t *testing.T) {
+	buf := new(bytes.Buffer)
+	slog.SetDefault(slog.New(slog.NewJSONHandler(buf, nil)))
+
+	// Authentication runs after the access log middleware and adds the caller to the request logger.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(WithAttrs(r.Context(), slog.String("subject", "alice"))))
+	})
+	middlewareHandler := Middleware(nextHandler)
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	req = req.WithContext(WithAttrs(req.Context(), slog.String("requestId", "r1")))
+	middlewareHandler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "request completed", record["msg"])
+	assert.Equal(t, "alice", record["subject"])
+	assert.Equal(t, "r1", record["requestId"])
+}
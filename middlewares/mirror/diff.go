@@ -0,0 +1,34 @@
+package mirror
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// Response is a primary or shadow response, compared by a DiffFunc.
+type Response struct {
+	Status    int         // Status code.
+	Header    http.Header // Headers.
+	Body      []byte      // Body, up to the maximum body size.
+	Truncated bool        // Flag set when the body was over the maximum body size.
+}
+
+// DiffFunc returns the differences between the primary and shadow responses to a request, none meaning a match.
+type DiffFunc func(r *http.Request, primary, shadow *Response) []string
+
+// DiffStatusAndBody is a DiffFunc comparing the status codes, content types and bodies of responses.
+// Truncated bodies aren't compared.
+func DiffStatusAndBody(_ *http.Request, primary, shadow *Response) []string {
+	var diffs []string
+	if primary.Status != shadow.Status {
+		diffs = append(diffs, fmt.Sprintf("status %d != %d", primary.Status, shadow.Status))
+	}
+	if p, s := primary.Header.Get("Content-Type"), shadow.Header.Get("Content-Type"); p != s {
+		diffs = append(diffs, fmt.Sprintf("content type %q != %q", p, s))
+	}
+	if !primary.Truncated && !shadow.Truncated && !bytes.Equal(primary.Body, shadow.Body) {
+		diffs = append(diffs, fmt.Sprintf("body of %d bytes != %d bytes", len(primary.Body), len(shadow.Body)))
+	}
+	return diffs
+}
@@ -0,0 +1,33 @@
+package mirror
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+)
+
+// outcomeKey is the attribute key for the outcome of a shadow request.
+const outcomeKey = attribute.Key("mirror.outcome")
+
+type Metrics struct {
+	shadowCounter metric.Int64Counter
+}
+
+// NewMetrics returns a new Metrics instance.
+func NewMetrics(meter *metric.Meter) *Metrics {
+	shadowCounter, _ := (*meter).Int64Counter(
+		"http_requests_mirrored_total",
+		metric.WithDescription("Total number of HTTP requests selected for mirroring to the shadow backend."),
+	)
+
+	return &Metrics{
+		shadowCounter: shadowCounter,
+	}
+}
+
+// IncreaseShadowCounter increases the mirrored request counter by 1.
+func (m *Metrics) IncreaseShadowCounter(ctx context.Context, method, outcome string) {
+	m.shadowCounter.Add(ctx, 1, metric.WithAttributes(semconv.HTTPMethodKey.String(method), outcomeKey.String(outcome)))
+}
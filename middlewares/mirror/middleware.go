@@ -0,0 +1,239 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/client"
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/logging"
+)
+
+// Header is the header set on shadow requests, so that shadow backends can skip side effects.
+const Header = "X-Shadow-Request"
+
+// Config is a struct that holds configuration options for the mirroring middleware.
+type Config struct {
+	Target           string             // Base URL of the shadow backend, such as "http://shadow.internal:8080".
+	DefaultRatio     float64            // Share of requests mirrored on paths without a route ratio, from 0 to 1.
+	RouteRatios      map[string]float64 // Shares of requests mirrored per path prefix.
+	MaxBodySize      int64              // Maximum size of request bodies mirrored and response bodies compared.
+	Timeout          time.Duration      // Timeout of each shadow request.
+	MaxInFlight      int                // Maximum number of concurrent shadow requests, others are dropped.
+	Client           *http.Client       // Client sending shadow requests, nil means an instrumented client.New.
+	Diff             DiffFunc           // Function comparing primary and shadow responses, nil disables it.
+	RequestHeaders   common.HeaderRules // Changes to the headers of shadow requests.
+	ExcludedPrefixes []string           // Path prefixes that are never mirrored.
+}
+
+// NewConfig creates a new Config struct with default values.
+// The Target must be set. Credentials aren't sent to the shadow backend, which may be less trusted.
+func NewConfig() *Config {
+	return &Config{
+		Target:           "",
+		DefaultRatio:     0,
+		RouteRatios:      map[string]float64{},
+		MaxBodySize:      1 << 20,
+		Timeout:          5 * time.Second,
+		MaxInFlight:      100,
+		Client:           nil,
+		Diff:             nil,
+		RequestHeaders:   common.HeaderRules{Remove: append([]string{}, common.CredentialHeaders...)},
+		ExcludedPrefixes: []string{},
+	}
+}
+
+// ratioFor returns the share of requests mirrored on the given path.
+func (c *Config) ratioFor(path string) float64 {
+	if _, ratio, ok := common.MatchPrefix(path, c.RouteRatios); ok {
+		return ratio
+	}
+	return c.DefaultRatio
+}
+
+// Shadow request outcomes, used as metric attribute values.
+const (
+	outcomeSent     = "sent"
+	outcomeSkipped  = "skipped"
+	outcomeDropped  = "dropped"
+	outcomeFailed   = "failed"
+	outcomeMismatch = "mismatch"
+)
+
+// Middleware is the mirroring middleware function that takes a Config struct and returns the middleware.
+// A sampled share of requests is copied to the shadow backend once the primary response is written, without
+// affecting it. Requests with a body over the maximum size aren't mirrored. It panics if the Target or
+// MaxInFlight are invalid.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	target, err := url.Parse(config.Target)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		panic("mirror: invalid target " + config.Target)
+	}
+	if config.MaxInFlight <= 0 {
+		panic("mirror: MaxInFlight must be positive")
+	}
+
+	httpClient := config.Client
+	if httpClient == nil {
+		httpClient = client.New(client.NewConfig())
+	}
+
+	var (
+		pkgName  = reflect.TypeOf(struct{}{}).PkgPath()
+		meter    = otel.GetMeterProvider().Meter(pkgName)
+		metrics  = NewMetrics(&meter)
+		inFlight = make(chan struct{}, config.MaxInFlight)
+	)
+
+	// shadow sends the shadow request and compares its response with the primary one, if recorded.
+	shadow := func(req *http.Request, primary *Response) {
+		ctx, cancel := context.WithTimeout(req.Context(), config.Timeout)
+		defer cancel()
+		logger := logging.FromContext(ctx)
+
+		resp, err := httpClient.Do(req.WithContext(ctx))
+		if err != nil {
+			metrics.IncreaseShadowCounter(ctx, req.Method, outcomeFailed)
+			logger.Warn("shadow request failed", slog.String("error", err.Error()))
+			return
+		}
+		defer resp.Body.Close()
+
+		if primary == nil || config.Diff == nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, config.MaxBodySize))
+			metrics.IncreaseShadowCounter(ctx, req.Method, outcomeSent)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, config.MaxBodySize+1))
+		if err != nil {
+			metrics.IncreaseShadowCounter(ctx, req.Method, outcomeFailed)
+			logger.Warn("shadow response read failed", slog.String("error", err.Error()))
+			return
+		}
+		shadowResp := &Response{Status: resp.StatusCode, Header: resp.Header, Body: body}
+		if int64(len(body)) > config.MaxBodySize {
+			shadowResp.Body, shadowResp.Truncated = body[:config.MaxBodySize], true
+		}
+
+		if diffs := config.Diff(req, primary, shadowResp); len(diffs) > 0 {
+			metrics.IncreaseShadowCounter(ctx, req.Method, outcomeMismatch)
+			logger.Warn("shadow response mismatch",
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.Any("diffs", diffs),
+			)
+			return
+		}
+		metrics.IncreaseShadowCounter(ctx, req.Method, outcomeSent)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) || rand.Float64() >= config.ratioFor(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, ok := bufferBody(r, config.MaxBodySize)
+			if !ok {
+				metrics.IncreaseShadowCounter(r.Context(), r.Method, outcomeSkipped)
+				next.ServeHTTP(w, r)
+				return
+			}
+			// The shadow request is built before the handler runs, so that it sees the request as received.
+			req := shadowRequest(r, target, body, config.RequestHeaders)
+
+			var tee *teeWriter
+			if config.Diff != nil {
+				tee = &teeWriter{ResponseWriter: w, maxBodySize: config.MaxBodySize}
+				w = tee
+			}
+			next.ServeHTTP(w, r)
+
+			select {
+			case inFlight <- struct{}{}:
+			default:
+				metrics.IncreaseShadowCounter(r.Context(), r.Method, outcomeDropped)
+				return
+			}
+
+			var primary *Response
+			if tee != nil {
+				primary = tee.response()
+			}
+			go func() {
+				defer func() { <-inFlight }()
+				shadow(req, primary)
+			}()
+		})
+	}
+}
+
+// bufferBody reads the request body, up to the maximum size, and replaces it with a copy for the handler.
+// It reports false, leaving the body readable as if untouched, when the body is over the maximum size.
+func bufferBody(r *http.Request, maxBodySize int64) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+	if r.ContentLength > maxBodySize {
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+	return body, err == nil && int64(len(body)) <= maxBodySize
+}
+
+// readCloser combines a reader with the closer of the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// shadowRequest returns a copy of the request, sent to the target with the body and outliving the request.
+func shadowRequest(r *http.Request, target *url.URL, body []byte, headers common.HeaderRules) *http.Request {
+	req := r.Clone(common.Detach(r.Context()))
+	req.RequestURI = ""
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	req.URL.Path = singleJoiningSlash(target.Path, r.URL.Path)
+	req.URL.RawPath = ""
+	req.Host = target.Host
+	headers.Apply(req.Header)
+	// The transport negotiates and decodes compression itself, so that shadow bodies compare with primary ones,
+	// which are recorded before the compression middleware.
+	req.Header.Del("Accept-Encoding")
+	req.Header.Set(Header, "true")
+
+	req.Body = http.NoBody
+	req.GetBody = nil
+	req.ContentLength = int64(len(body))
+	if len(body) > 0 {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	return req
+}
+
+// singleJoiningSlash joins URL paths with a single slash.
+func singleJoiningSlash(a, b string) string {
+	switch aslash, bslash := len(a) > 0 && a[len(a)-1] == '/', len(b) > 0 && b[0] == '/'; {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash && a != "" && b != "":
+		return a + "/" + b
+	}
+	return a + b
+}
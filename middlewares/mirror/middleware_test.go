@@ -0,0 +1,256 @@
+package mirror
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shadowServer records the requests it receives and answers with the given status and body.
+type shadowServer struct {
+	*httptest.Server
+	requests chan *http.Request
+	bodies   chan string
+}
+
+func newShadowServer(t *testing.T, status int, body string, delay time.Duration) *shadowServer {
+	s := &shadowServer{requests: make(chan *http.Request, 10), bodies: make(chan string, 10)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		s.requests <- r
+		s.bodies <- string(b)
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("ok " + string(body)))
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		ratio    float64
+		routes   map[string]float64
+		path     string
+		body     string
+		excluded []string
+		mirrored bool
+	}{
+		{name: "Mirrored", ratio: 1, path: "/orders?id=1", body: "item=1", mirrored: true},
+		{name: "Not sampled", ratio: 0, path: "/orders", body: "item=1", mirrored: false},
+		{name: "Route ratio", ratio: 0, routes: map[string]float64{"/orders": 1}, path: "/orders/1", mirrored: true},
+		{name: "Route ratio zero", ratio: 1, routes: map[string]float64{"/admin": 0}, path: "/admin/users", mirrored: false},
+		{name: "Excluded", ratio: 1, path: "/health", excluded: []string{"/health"}, mirrored: false},
+		{name: "Body too large", ratio: 1, path: "/orders", body: strings.Repeat("x", 32), mirrored: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shadow := newShadowServer(t, http.StatusOK, "ok", 0)
+			config := NewConfig()
+			config.Target = shadow.URL
+			config.DefaultRatio = tt.ratio
+			if tt.routes != nil {
+				config.RouteRatios = tt.routes
+			}
+			config.MaxBodySize = 16
+			config.ExcludedPrefixes = tt.excluded
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer token")
+			req.Header.Set("X-Trace", "abc")
+			rr := httptest.NewRecorder()
+			Middleware(config)(echoHandler()).ServeHTTP(rr, req)
+
+			// The primary response is never affected.
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "ok "+tt.body, rr.Body.String())
+
+			select {
+			case r := <-shadow.requests:
+				require.True(t, tt.mirrored, "unexpected shadow request")
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, req.URL.RequestURI(), r.URL.RequestURI())
+				assert.Equal(t, "true", r.Header.Get(Header))
+				assert.Empty(t, r.Header.Get("Authorization"))
+				assert.Equal(t, "abc", r.Header.Get("X-Trace"))
+				assert.Equal(t, tt.body, <-shadow.bodies)
+			case <-time.After(200 * time.Millisecond):
+				assert.False(t, tt.mirrored, "missing shadow request")
+			}
+		})
+	}
+}
+
+func TestMiddlewareDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		diffs  []string
+	}{
+		{name: "Match", status: http.StatusOK, body: "ok item=1"},
+		{name: "Status mismatch", status: http.StatusInternalServerError, body: "ok item=1", diffs: []string{"status 200 != 500"}},
+		{name: "Body mismatch", status: http.StatusOK, body: "ko", diffs: []string{"body of 9 bytes != 2 bytes"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shadow := newShadowServer(t, tt.status, tt.body, 0)
+			done := make(chan []string, 1)
+			config := NewConfig()
+			config.Target = shadow.URL
+			config.DefaultRatio = 1
+			config.Diff = func(r *http.Request, primary, shadow *Response) []string {
+				diffs := DiffStatusAndBody(r, primary, shadow)
+				done <- diffs
+				return diffs
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("item=1"))
+			Middleware(config)(echoHandler()).ServeHTTP(httptest.NewRecorder(), req)
+
+			select {
+			case diffs := <-done:
+				assert.Equal(t, tt.diffs, diffs)
+			case <-time.After(time.Second):
+				t.Fatal("missing diff")
+			}
+		})
+	}
+}
+
+func TestMiddlewareCompressedShadow(t *testing.T) {
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			_, _ = w.Write([]byte("ok "))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gw := gzip.NewWriter(w)
+		_, _ = gw.Write([]byte("ok "))
+		_ = gw.Close()
+	}))
+	t.Cleanup(shadow.Close)
+
+	done := make(chan []string, 1)
+	config := NewConfig()
+	config.Target = shadow.URL
+	config.DefaultRatio = 1
+	config.Diff = func(r *http.Request, primary, shadow *Response) []string {
+		diffs := DiffStatusAndBody(r, primary, shadow)
+		done <- diffs
+		return diffs
+	}
+
+	// The primary body is recorded uncompressed, as the compression middleware runs outside.
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	Middleware(config)(echoHandler()).ServeHTTP(httptest.NewRecorder(), req)
+
+	select {
+	case diffs := <-done:
+		assert.Empty(t, diffs)
+	case <-time.After(time.Second):
+		t.Fatal("missing diff")
+	}
+}
+
+func TestMiddlewareLimits(t *testing.T) {
+	shadow := newShadowServer(t, http.StatusOK, "ok", 100*time.Millisecond)
+	config := NewConfig()
+	config.Target = shadow.URL
+	config.DefaultRatio = 1
+	config.MaxInFlight = 1
+	config.Timeout = 50 * time.Millisecond
+
+	handler := Middleware(config)(echoHandler())
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+	}
+
+	// The primary requests don't wait for the shadow backend, and shadows over the limit are dropped.
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	<-shadow.requests
+	select {
+	case <-shadow.requests:
+		t.Fatal("shadow request over the limit")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	shadow := newShadowServer(t, http.StatusOK, "ok", 0)
+	config := NewConfig()
+	config.Target = shadow.URL
+	config.DefaultRatio = 1
+	config.RequestHeaders.Remove = append(config.RequestHeaders.Remove, "X-Tenant")
+	config.RequestHeaders.Set = map[string]string{"Authorization": "Bearer shadow"}
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Cookie", "__Host-session=abc")
+	req.Header.Set("X-API-Key", "key")
+	req.Header.Set("X-Tenant", "acme")
+	Middleware(config)(echoHandler()).ServeHTTP(httptest.NewRecorder(), req)
+
+	r := <-shadow.requests
+	assert.Equal(t, "Bearer shadow", r.Header.Get("Authorization"))
+	assert.Empty(t, r.Header.Get("Cookie"))
+	assert.Empty(t, r.Header.Get("X-API-Key"))
+	assert.Empty(t, r.Header.Get("X-Tenant"))
+}
+
+func TestMiddlewareInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		maxInFlight int
+	}{
+		{"Target without scheme", "shadow.internal", 100},
+		{"No in-flight shadow requests", "http://shadow.internal", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.Target = tt.target
+			config.MaxInFlight = tt.maxInFlight
+			assert.Panics(t, func() { Middleware(config) })
+		})
+	}
+}
+
+func TestSingleJoiningSlash(t *testing.T) {
+	tests := []struct {
+		a, b, expected string
+	}{
+		{"", "/orders", "/orders"},
+		{"/v2", "/orders", "/v2/orders"},
+		{"/v2/", "/orders", "/v2/orders"},
+		{"/v2", "orders", "/v2/orders"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, singleJoiningSlash(tt.a, tt.b))
+	}
+}
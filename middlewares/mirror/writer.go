@@ -0,0 +1,57 @@
+package mirror
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// teeWriter is a http.ResponseWriter recording the primary response for comparison, up to a maximum body size.
+type teeWriter struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	maxBodySize int64
+	truncated   bool
+}
+
+// WriteHeader implements http.ResponseWriter and records the status code.
+func (w *teeWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter and records the body.
+func (w *teeWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.truncated {
+		if remaining := w.maxBodySize - int64(w.body.Len()); int64(len(b)) <= remaining {
+			w.body.Write(b)
+		} else {
+			w.truncated = true
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *teeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// response returns the recorded primary response.
+func (w *teeWriter) response() *Response {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &Response{
+		Status:    status,
+		Header:    w.Header().Clone(),
+		Body:      w.body.Bytes(),
+		Truncated: w.truncated,
+	}
+}
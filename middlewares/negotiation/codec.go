@@ -0,0 +1,183 @@
+package negotiation
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrUnsupportedValue is returned by codecs that can't represent a value, such as Protobuf for other values than
+// proto.Message. Respond then tries the next acceptable codec.
+var ErrUnsupportedValue = errors.New("value not supported by codec")
+
+// Codec encodes and decodes values in a media type.
+type Codec interface {
+	// ContentType returns the media type of the codec, such as "application/json".
+	ContentType() string
+	// Encode writes the encoding of v.
+	Encode(w io.Writer, v any) error
+	// Decode reads an encoded value into v.
+	Decode(r io.Reader, v any) error
+}
+
+// Built-in codecs.
+var (
+	JSON        Codec = jsonCodec{}     // JSON, using protojson for proto.Message values.
+	XML         Codec = xmlCodec{}      // XML.
+	NDJSON      Codec = ndjsonCodec{}   // Newline delimited JSON, one line per element of slices.
+	CBOR        Codec = cborCodec{}     // CBOR (RFC 8949).
+	MessagePack Codec = msgpackCodec{}  // MessagePack.
+	Protobuf    Codec = protobufCodec{} // Protocol Buffers binary format, for proto.Message values only.
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	if m, ok := v.(proto.Message); ok {
+		b, err := protojson.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+
+	err := json.NewEncoder(w).Encode(v)
+	var unsupported *json.UnsupportedTypeError
+	if errors.As(err, &unsupported) {
+		return fmt.Errorf("%w: %v", ErrUnsupportedValue, err)
+	}
+	return err
+}
+
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	if m, ok := v.(proto.Message); ok {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return protojson.Unmarshal(b, m)
+	}
+	return json.NewDecoder(r).Decode(v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string { return "application/xml" }
+
+func (xmlCodec) Encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	err := xml.NewEncoder(w).Encode(v)
+	var unsupported *xml.UnsupportedTypeError
+	if errors.As(err, &unsupported) {
+		return fmt.Errorf("%w: %v", ErrUnsupportedValue, err)
+	}
+	return err
+}
+
+func (xmlCodec) Decode(r io.Reader, v any) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+type ndjsonCodec struct{}
+
+func (ndjsonCodec) ContentType() string { return "application/x-ndjson" }
+
+func (ndjsonCodec) Encode(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return enc.Encode(v)
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode appends each line to v, which must be a pointer to a slice.
+func (ndjsonCodec) Decode(r io.Reader, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%w: %T is not a pointer to a slice", ErrUnsupportedValue, v)
+	}
+
+	slice := rv.Elem()
+	dec := json.NewDecoder(r)
+	for {
+		elem := reflect.New(slice.Type().Elem())
+		if err := dec.Decode(elem.Interface()); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, elem.Elem()))
+	}
+}
+
+type cborCodec struct{}
+
+func (cborCodec) ContentType() string { return "application/cbor" }
+
+func (cborCodec) Encode(w io.Writer, v any) error {
+	return cbor.NewEncoder(w).Encode(v)
+}
+
+func (cborCodec) Decode(r io.Reader, v any) error {
+	return cbor.NewDecoder(r).Decode(v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	return msgpack.NewEncoder(w).Encode(v)
+}
+
+func (msgpackCodec) Decode(r io.Reader, v any) error {
+	return msgpack.NewDecoder(r).Decode(v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string { return "application/x-protobuf" }
+
+func (protobufCodec) Encode(w io.Writer, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T is not a proto.Message", ErrUnsupportedValue, v)
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (protobufCodec) Decode(r io.Reader, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T is not a proto.Message", ErrUnsupportedValue, v)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, m)
+}
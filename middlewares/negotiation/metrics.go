@@ -0,0 +1,33 @@
+package negotiation
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+)
+
+// reasonKey is the attribute key for the content negotiation rejection reason.
+const reasonKey = attribute.Key("negotiation.reason")
+
+type Metrics struct {
+	rejectedCounter metric.Int64Counter
+}
+
+// NewMetrics returns a new Metrics instance.
+func NewMetrics(meter *metric.Meter) *Metrics {
+	rejectedCounter, _ := (*meter).Int64Counter(
+		"http_requests_negotiation_rejected_total",
+		metric.WithDescription("Total number of HTTP requests rejected by content negotiation."),
+	)
+
+	return &Metrics{
+		rejectedCounter: rejectedCounter,
+	}
+}
+
+// IncreaseRejectedCounter increases the rejected request counter by 1.
+func (m *Metrics) IncreaseRejectedCounter(ctx context.Context, method, reason string) {
+	m.rejectedCounter.Add(ctx, 1, metric.WithAttributes(semconv.HTTPMethodKey.String(method), reasonKey.String(reason)))
+}
@@ -0,0 +1,149 @@
+package negotiation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// ErrUnsupportedMediaType is returned by Decode when no codec matches the request Content-Type.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// Config is a struct that holds configuration options for the content negotiation middleware.
+type Config struct {
+	Registry         *Registry // Codecs responses are encoded and requests decoded with.
+	ExcludedPrefixes []string  // Path prefixes that are not negotiated, such as static files or forms.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		Registry:         DefaultRegistry,
+		ExcludedPrefixes: []string{},
+	}
+}
+
+// Rejection reasons, used as metric attribute values.
+const (
+	reasonNotAcceptable        = "not_acceptable"
+	reasonUnsupportedMediaType = "unsupported_media_type"
+)
+
+type registryKey struct{}
+
+// registryFromContext returns the registry of the middleware, or DefaultRegistry.
+func registryFromContext(ctx context.Context) *Registry {
+	if registry, ok := ctx.Value(registryKey{}).(*Registry); ok {
+		return registry
+	}
+	return DefaultRegistry
+}
+
+// Middleware is the content negotiation middleware function that takes a Config struct and returns the middleware.
+// Requests accepting none of the registry media types get a 406, and requests with a body of none of them a 415.
+// Handlers then encode responses with Respond and decode requests with Decode.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	var (
+		pkgName = reflect.TypeOf(struct{}{}).PkgPath()
+		meter   = otel.GetMeterProvider().Meter(pkgName)
+		metrics = NewMetrics(&meter)
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(config.Registry.Acceptable(r.Header.Get("Accept"))) == 0 {
+				metrics.IncreaseRejectedCounter(r.Context(), r.Method, reasonNotAcceptable)
+				notAcceptable(w, config.Registry)
+				return
+			}
+
+			if r.ContentLength != 0 {
+				if _, ok := config.Registry.Lookup(r.Header.Get("Content-Type")); !ok {
+					metrics.IncreaseRejectedCounter(r.Context(), r.Method, reasonUnsupportedMediaType)
+					unsupportedMediaType(w, r, config.Registry)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), registryKey{}, config.Registry)))
+		})
+	}
+}
+
+// Respond writes v with the status code, encoded with the most acceptable codec able to represent it.
+// It writes a 406 problem if there is none.
+func Respond(w http.ResponseWriter, r *http.Request, status int, v any) {
+	registry := registryFromContext(r.Context())
+	w.Header().Add("Vary", "Accept")
+
+	var buf bytes.Buffer
+	for _, codec := range registry.Acceptable(r.Header.Get("Accept")) {
+		buf.Reset()
+		err := codec.Encode(&buf, v)
+		if errors.Is(err, ErrUnsupportedValue) {
+			continue
+		}
+		if err != nil {
+			slog.Error("response encoding failed",
+				slog.String("contentType", codec.ContentType()),
+				slog.String("error", err.Error()),
+			)
+			problem.Error(w, http.StatusInternalServerError, "response encoding failed")
+			return
+		}
+
+		w.Header().Set("Content-Type", codec.ContentType())
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.WriteHeader(status)
+		_, _ = io.Copy(w, &buf)
+		return
+	}
+
+	notAcceptable(w, registry)
+}
+
+// Decode reads the request body into v with the codec of its Content-Type.
+// It returns ErrUnsupportedMediaType if no codec matches.
+func Decode(r *http.Request, v any) error {
+	contentType := r.Header.Get("Content-Type")
+	codec, ok := registryFromContext(r.Context()).Lookup(contentType)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnsupportedMediaType, contentType)
+	}
+	return codec.Decode(r.Body, v)
+}
+
+// notAcceptable writes a 406 problem listing the available media types.
+func notAcceptable(w http.ResponseWriter, registry *Registry) {
+	problem.Error(w, http.StatusNotAcceptable, "available types are "+strings.Join(registry.ContentTypes(), ", "))
+}
+
+// unsupportedMediaType writes a 415 problem listing the accepted media types, also advertised in the
+// Accept-Post or Accept-Patch header (RFC 5789).
+func unsupportedMediaType(w http.ResponseWriter, r *http.Request, registry *Registry) {
+	types := strings.Join(registry.ContentTypes(), ", ")
+	switch r.Method {
+	case http.MethodPost:
+		w.Header().Set("Accept-Post", types)
+	case http.MethodPatch:
+		w.Header().Set("Accept-Patch", types)
+	}
+	problem.Error(w, http.StatusUnsupportedMediaType, "accepted types are "+types)
+}
@@ -0,0 +1,138 @@
+package negotiation
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type pet struct {
+	Name string `json:"name" xml:"name" cbor:"name" msgpack:"name"`
+}
+
+func TestMiddleware(t *testing.T) {
+	config := NewConfig()
+	config.ExcludedPrefixes = []string{"/static"}
+
+	tests := []struct {
+		name            string
+		method          string
+		target          string
+		accept          string
+		contentType     string
+		body            string
+		wantStatus      int
+		wantContentType string
+		wantAcceptPost  string
+	}{
+		{"Default type", http.MethodGet, "/pets", "", "", "", http.StatusOK, "application/json", ""},
+		{"Preferred type", http.MethodGet, "/pets", "application/xml, application/json;q=0.9", "", "", http.StatusOK, "application/xml", ""},
+		{"Not acceptable", http.MethodGet, "/pets", "text/html", "", "", http.StatusNotAcceptable, "application/problem+json", ""},
+		{"Decoded body", http.MethodPost, "/pets", "", "application/json", `{"name":"Rex"}`, http.StatusOK, "application/json", ""},
+		{"Unsupported media type", http.MethodPost, "/pets", "", "text/plain", "Rex", http.StatusUnsupportedMediaType, "application/problem+json",
+			"application/json, application/xml, application/x-ndjson, application/cbor, application/msgpack, application/x-protobuf"},
+		{"Excluded path", http.MethodGet, "/static/index.html", "text/html", "", "", http.StatusOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasPrefix(r.URL.Path, "/static") {
+					return
+				}
+				v := pet{Name: "Rex"}
+				if r.ContentLength > 0 {
+					assert.NoError(t, Decode(r, &v))
+				}
+				Respond(w, r, http.StatusOK, v)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantContentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantAcceptPost, rr.Header().Get("Accept-Post"))
+		})
+	}
+}
+
+func TestRespondCodecs(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          string
+		value           any
+		wantStatus      int
+		wantContentType string
+	}{
+		{"CBOR", "application/cbor", pet{Name: "Rex"}, http.StatusOK, "application/cbor"},
+		{"MessagePack", "application/msgpack", pet{Name: "Rex"}, http.StatusOK, "application/msgpack"},
+		{"NDJSON", "application/x-ndjson", []pet{{Name: "Rex"}, {Name: "Tom"}}, http.StatusOK, "application/x-ndjson"},
+		{"Protobuf", "application/x-protobuf", wrapperspb.String("Rex"), http.StatusOK, "application/x-protobuf"},
+		{"Protobuf fallback", "application/x-protobuf, application/json;q=0.5", pet{Name: "Rex"}, http.StatusOK, "application/json"},
+		{"No codec for value", "application/x-protobuf", pet{Name: "Rex"}, http.StatusNotAcceptable, "application/problem+json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", tt.accept)
+			rr := httptest.NewRecorder()
+
+			Respond(rr, req, http.StatusOK, tt.value)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantContentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", rr.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, codec := range []Codec{JSON, XML, CBOR, MessagePack} {
+		t.Run(codec.ContentType(), func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, codec.Encode(&buf, pet{Name: "Rex"}))
+
+			var got pet
+			assert.NoError(t, codec.Decode(&buf, &got))
+			assert.Equal(t, pet{Name: "Rex"}, got)
+		})
+	}
+
+	t.Run("NDJSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		want := []pet{{Name: "Rex"}, {Name: "Tom"}}
+		assert.NoError(t, NDJSON.Encode(&buf, want))
+		assert.Equal(t, "{\"name\":\"Rex\"}\n{\"name\":\"Tom\"}\n", buf.String())
+
+		var got []pet
+		assert.NoError(t, NDJSON.Decode(&buf, &got))
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("Protobuf and protojson", func(t *testing.T) {
+		for _, codec := range []Codec{Protobuf, JSON} {
+			var buf bytes.Buffer
+			assert.NoError(t, codec.Encode(&buf, wrapperspb.String("Rex")))
+
+			got := &wrapperspb.StringValue{}
+			assert.NoError(t, codec.Decode(&buf, got))
+			assert.True(t, proto.Equal(wrapperspb.String("Rex"), got))
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package negotiation
+
+import (
+	"mime"
+	"sort"
+	"strings"
+)
+
+// Registry is an ordered set of codecs, the order breaking ties between equally acceptable media types.
+type Registry struct {
+	codecs []Codec
+}
+
+// NewRegistry returns a Registry of the codecs, in order of preference.
+func NewRegistry(codecs ...Codec) *Registry {
+	return &Registry{codecs: codecs}
+}
+
+// DefaultRegistry holds the built-in codecs, preferring JSON.
+var DefaultRegistry = NewRegistry(JSON, XML, NDJSON, CBOR, MessagePack, Protobuf)
+
+// ContentTypes returns the media types of the codecs, in order of preference.
+func (r *Registry) ContentTypes() []string {
+	types := make([]string, len(r.codecs))
+	for i, codec := range r.codecs {
+		types[i] = codec.ContentType()
+	}
+	return types
+}
+
+// Acceptable returns the codecs acceptable for an Accept header, most acceptable first.
+// Every codec is acceptable when the header is empty.
+func (r *Registry) Acceptable(accept string) []Codec {
+	if strings.TrimSpace(accept) == "" {
+		return r.codecs
+	}
+
+	ranges := parseAccept(accept)
+	type candidate struct {
+		codec Codec
+		q     float64
+	}
+	var candidates []candidate
+	for _, codec := range r.codecs {
+		if q := quality(ranges, codec.ContentType()); q > 0 {
+			candidates = append(candidates, candidate{codec: codec, q: q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	codecs := make([]Codec, len(candidates))
+	for i, c := range candidates {
+		codecs[i] = c.codec
+	}
+	return codecs
+}
+
+// Lookup returns the codec of a Content-Type header, ignoring its parameters.
+func (r *Registry) Lookup(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	for _, codec := range r.codecs {
+		if codec.ContentType() == mediaType {
+			return codec, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,56 @@
+package negotiation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryAcceptable(t *testing.T) {
+	registry := NewRegistry(JSON, XML, CBOR)
+
+	tests := []struct {
+		name   string
+		accept string
+		want   []string
+	}{
+		{"Empty header", "", []string{"application/json", "application/xml", "application/cbor"}},
+		{"Exact type", "application/xml", []string{"application/xml"}},
+		{"Quality order", "application/json;q=0.5, application/cbor", []string{"application/cbor", "application/json"}},
+		{"Subtype wildcard", "application/*;q=0.8", []string{"application/json", "application/xml", "application/cbor"}},
+		{"Specific range wins", "application/*, application/xml;q=0", []string{"application/json", "application/cbor"}},
+		{"Any type", "text/html, */*;q=0.1", []string{"application/json", "application/xml", "application/cbor"}},
+		{"Nothing acceptable", "text/html", []string{}},
+		{"Invalid ranges skipped", "application/json;q=2, */json, application/xml", []string{"application/xml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, codec := range registry.Acceptable(tt.accept) {
+				got = append(got, codec.ContentType())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		wantOK      bool
+	}{
+		{"Exact type", "application/json", true},
+		{"With parameters", "application/json; charset=utf-8", true},
+		{"Unknown type", "text/plain", false},
+		{"Missing type", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := DefaultRegistry.Lookup(tt.contentType)
+			assert.Equal(t, tt.wantOK, ok)
+		})
+	}
+}
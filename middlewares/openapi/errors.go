@@ -0,0 +1,82 @@
+package openapi
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// fieldErrors converts validation errors into problem field errors, locating each failing field.
+func fieldErrors(err error) []problem.FieldError {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var fields []problem.FieldError
+		for _, inner := range e {
+			fields = append(fields, fieldErrors(inner)...)
+		}
+		return fields
+	case *openapi3filter.RequestError:
+		if e.Parameter == nil {
+			return bodyErrors(e.Err, e.Reason)
+		}
+		field := problem.FieldError{Detail: reason(e.Err, e.Reason)}
+		if e.Parameter.In == openapi3.ParameterInHeader {
+			field.Header = e.Parameter.Name
+		} else {
+			field.Parameter = e.Parameter.Name
+		}
+		return []problem.FieldError{field}
+	case *openapi3filter.ResponseError:
+		return bodyErrors(e.Err, e.Reason)
+	default:
+		return []problem.FieldError{{Detail: err.Error()}}
+	}
+}
+
+// bodyErrors converts body validation errors into problem field errors located by JSON pointers.
+func bodyErrors(err error, fallback string) []problem.FieldError {
+	if multi, ok := err.(openapi3.MultiError); ok {
+		var fields []problem.FieldError
+		for _, inner := range multi {
+			fields = append(fields, bodyErrors(inner, fallback)...)
+		}
+		return fields
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return []problem.FieldError{{Pointer: pointer(schemaErr.JSONPointer()), Detail: schemaErr.Reason}}
+	}
+	return []problem.FieldError{{Pointer: "#", Detail: reason(err, fallback)}}
+}
+
+// reason returns the most specific explanation of a validation error, preferring schema reasons which never
+// include the failing value.
+func reason(err error, fallback string) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return schemaErr.Reason
+	}
+	if err != nil {
+		return err.Error()
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return "invalid value"
+}
+
+// pointer returns the RFC 6901 JSON pointer, as a URI fragment, of the path segments.
+func pointer(path []string) string {
+	var b strings.Builder
+	b.WriteString("#")
+	for _, segment := range path {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(segment))
+	}
+	return b.String()
+}
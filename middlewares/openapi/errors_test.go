@@ -0,0 +1,25 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointer(t *testing.T) {
+	tests := []struct {
+		name string
+		path []string
+		want string
+	}{
+		{"Root", nil, "#"},
+		{"Nested", []string{"items", "0", "name"}, "#/items/0/name"},
+		{"Escaped", []string{"a/b", "c~d"}, "#/a~1b/c~0d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pointer(tt.path))
+		})
+	}
+}
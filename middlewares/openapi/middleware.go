@@ -0,0 +1,187 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/logging"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// operationIDKey is the attribute key for the OpenAPI operation ID.
+const operationIDKey = attribute.Key("openapi.operation_id")
+
+// Config is a struct that holds configuration options for the OpenAPI validation middleware.
+type Config struct {
+	Document          *openapi3.T // OpenAPI 3 document, such as returned by Load.
+	ValidateResponses bool        // Flag to replace responses not matching the document with errors, meant for tests.
+	RejectUnknown     bool        // Flag to reject requests matching no path of the document, instead of passing them.
+	ExcludedPrefixes  []string    // Path prefixes that are not validated.
+}
+
+// NewConfig creates a new Config struct with default values.
+// The Document must be set.
+func NewConfig() *Config {
+	return &Config{
+		Document:          nil,
+		ValidateResponses: false,
+		RejectUnknown:     false,
+		ExcludedPrefixes:  []string{},
+	}
+}
+
+// Load reads and validates the OpenAPI 3 document at path, in JSON or YAML.
+func Load(path string) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load OpenAPI document %s: %w", path, err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+type operationKey struct{}
+
+// OperationID returns the ID of the OpenAPI operation the request matched.
+func OperationID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(operationKey{}).(string)
+	return id, ok
+}
+
+// Middleware is the OpenAPI validation middleware function that takes a Config struct and returns the middleware.
+// Requests are validated against the parameters and body schema of the matched operation, whose ID is added to
+// the request logger and span. Invalid requests get a problem response listing the failing fields.
+// Security requirements are left to the authentication middlewares. It panics if the document is invalid.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	router, err := gorillamux.NewRouter(config.Document)
+	if err != nil {
+		panic(fmt.Errorf("OpenAPI router: %w", err))
+	}
+
+	options := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			route, pathParams, err := router.FindRoute(r)
+			switch {
+			case errors.Is(err, routers.ErrMethodNotAllowed):
+				problem.Error(w, http.StatusMethodNotAllowed, "method "+r.Method+" not allowed")
+				return
+			case err != nil && config.RejectUnknown:
+				problem.Error(w, http.StatusNotFound, "no operation matches "+r.URL.Path)
+				return
+			case err != nil:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			if id := route.Operation.OperationID; id != "" {
+				ctx = context.WithValue(ctx, operationKey{}, id)
+				ctx = logging.WithAttrs(ctx, slog.String("operationId", id))
+				trace.SpanFromContext(ctx).SetAttributes(operationIDKey.String(id), semconv.HTTPRouteKey.String(route.Path))
+			}
+			r = r.WithContext(ctx)
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			}
+			if err := openapi3filter.ValidateRequest(ctx, input); err != nil {
+				writeValidationProblem(w, http.StatusBadRequest, "request doesn't match the API specification", err)
+				return
+			}
+
+			if !config.ValidateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &responseRecorder{header: http.Header{}}
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+
+			responseInput := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 rec.status,
+				Header:                 rec.header,
+				Options:                &openapi3filter.Options{MultiError: true, IncludeResponseStatus: true},
+			}
+			responseInput.SetBodyBytes(rec.body.Bytes())
+			if err := openapi3filter.ValidateResponse(ctx, responseInput); err != nil {
+				logging.FromContext(ctx).Error("response doesn't match the API specification", slog.String("error", err.Error()))
+				writeValidationProblem(w, http.StatusInternalServerError, "response doesn't match the API specification", err)
+				return
+			}
+
+			for name, values := range rec.header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(rec.status)
+			_, _ = io.Copy(w, &rec.body)
+		})
+	}
+}
+
+// writeValidationProblem writes a problem response listing the failing fields of the validation error.
+func writeValidationProblem(w http.ResponseWriter, status int, detail string, err error) {
+	p := problem.New(status, detail)
+	p.Errors = fieldErrors(err)
+	problem.Write(w, p)
+}
+
+// responseRecorder is a http.ResponseWriter buffering the response for validation.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header implements http.ResponseWriter.
+func (w *responseRecorder) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements http.ResponseWriter and records the status code.
+func (w *responseRecorder) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+// Write implements http.ResponseWriter and records the body.
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
@@ -0,0 +1,177 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+const spec = `
+openapi: 3.0.3
+info:
+  title: Pets
+  version: "1.0"
+paths:
+  /pets:
+    post:
+      operationId: createPet
+      parameters:
+        - name: X-Tenant
+          in: header
+          required: true
+          schema:
+            type: string
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                  minLength: 1
+                tags:
+                  type: array
+                  items:
+                    type: string
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id]
+                properties:
+                  id:
+                    type: integer
+  /pets/{id}:
+    get:
+      operationId: getPet
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+      responses:
+        "200":
+          description: OK
+`
+
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData([]byte(spec))
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(loader.Context))
+	return doc
+}
+
+func TestMiddleware(t *testing.T) {
+	config := NewConfig()
+	config.Document = loadSpec(t)
+	config.RejectUnknown = true
+	config.ExcludedPrefixes = []string{"/health"}
+
+	tests := []struct {
+		name            string
+		method          string
+		target          string
+		tenant          string
+		body            string
+		wantStatus      int
+		wantOperationID string
+		wantErrors      []problem.FieldError
+	}{
+		{"Valid body", http.MethodPost, "/pets", "acme", `{"name":"Rex"}`, http.StatusOK, "createPet", nil},
+		{"Valid path parameter", http.MethodGet, "/pets/42", "", "", http.StatusOK, "getPet", nil},
+		{"Invalid path parameter", http.MethodGet, "/pets/rex", "", "", http.StatusBadRequest, "",
+			[]problem.FieldError{{Parameter: "id", Detail: "value rex: an invalid integer: invalid syntax"}}},
+		{"Missing header", http.MethodPost, "/pets", "", `{"name":"Rex"}`, http.StatusBadRequest, "",
+			[]problem.FieldError{{Header: "X-Tenant", Detail: "value is required but missing"}}},
+		{"Invalid body fields", http.MethodPost, "/pets", "acme", `{"name":"","tags":[1]}`, http.StatusBadRequest, "",
+			[]problem.FieldError{
+				{Pointer: "#/name", Detail: "minimum string length is 1"},
+				{Pointer: "#/tags/0", Detail: `value must be a string`},
+			}},
+		{"Method not allowed", http.MethodDelete, "/pets", "", "", http.StatusMethodNotAllowed, "", nil},
+		{"Unknown path", http.MethodGet, "/owners", "", "", http.StatusNotFound, "", nil},
+		{"Excluded path", http.MethodGet, "/health", "", "", http.StatusOK, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotOperationID string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotOperationID, _ = OperationID(r.Context())
+			})
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.tenant != "" {
+				req.Header.Set("X-Tenant", tt.tenant)
+			}
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantOperationID, gotOperationID)
+			if tt.wantErrors != nil {
+				var got problem.Details
+				assert.NoError(t, json.NewDecoder(rr.Body).Decode(&got))
+				assert.Equal(t, tt.wantErrors, got.Errors)
+			}
+		})
+	}
+}
+
+func TestMiddlewareValidateResponses(t *testing.T) {
+	config := NewConfig()
+	config.Document = loadSpec(t)
+	config.ValidateResponses = true
+
+	tests := []struct {
+		name       string
+		response   string
+		wantStatus int
+	}{
+		{"Valid response", `{"id":1}`, http.StatusCreated},
+		{"Invalid response", `{"id":"one"}`, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(tt.response))
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/pets", strings.NewReader(`{"name":"Rex"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Tenant", "acme")
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusCreated {
+				assert.Equal(t, tt.response, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of problem details responses.
+const ContentType = "application/problem+json"
+
+// Details is an RFC 9457 problem details object.
+type Details struct {
+	Type     string `json:"type,omitempty"`     // URI reference identifying the problem type.
+	Title    string `json:"title"`              // Short, human-readable summary of the problem type.
+	Status   int    `json:"status"`             // HTTP status code of this occurrence.
+	Detail   string `json:"detail,omitempty"`   // Human-readable explanation of this occurrence.
+	Instance string `json:"instance,omitempty"` // URI reference identifying this occurrence.
+
+	Errors []FieldError `json:"errors,omitempty"` // Validation errors of this occurrence.
+}
+
+// FieldError is a validation error of a request or response field, located by a JSON pointer into the body,
+// or by the name of a parameter or header.
+type FieldError struct {
+	Pointer   string `json:"pointer,omitempty"`   // JSON pointer to the failing body field, such as "#/items/0/name".
+	Parameter string `json:"parameter,omitempty"` // Name of the failing path, query or cookie parameter.
+	Header    string `json:"header,omitempty"`    // Name of the failing header.
+	Detail    string `json:"detail"`              // Human-readable explanation of the error.
+}
+
+// New returns a new Details with the title derived from the status code.
+func New(status int, detail string) *Details {
+	return &Details{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Write writes the problem details to the response with the matching status code.
+func Write(w http.ResponseWriter, p *Details) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// Error writes a problem details response built from the status code and detail.
+func Error(w http.ResponseWriter, status int, detail string) {
+	Write(w, New(status, detail))
+}
@@ -0,0 +1,38 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		detail    string
+		wantTitle string
+	}{
+		{"Payload too large", http.StatusRequestEntityTooLarge, "body exceeds 10 bytes", "Request Entity Too Large"},
+		{"Forbidden without detail", http.StatusForbidden, "", "Forbidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			Error(rr, tt.status, tt.detail)
+
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, ContentType, rr.Header().Get("Content-Type"))
+
+			var got Details
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&got))
+			assert.Equal(t, tt.wantTitle, got.Title)
+			assert.Equal(t, tt.status, got.Status)
+			assert.Equal(t, tt.detail, got.Detail)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/2n3g5c9/go-http/middlewares/forwarded"
+)
+
+// KeyFunc is a function type that returns the rate limit key of a request.
+// Requests with an empty key are not rate limited.
+type KeyFunc func(*http.Request) string
+
+// KeyByIP keys requests on the client IP address, as resolved behind trusted proxies.
+func KeyByIP(r *http.Request) string {
+	return forwarded.ClientAddress(r)
+}
+
+// KeyByRoute keys requests on the request path, sharing the limit between all clients.
+func KeyByRoute(r *http.Request) string {
+	return r.URL.Path
+}
+
+// KeyByHeader returns a KeyFunc keying requests on the hashed value of the given header, such as an API key.
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		value := r.Header.Get(name)
+		if value == "" {
+			return ""
+		}
+
+		// Hash the value so secrets are never kept in the limiter state.
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:])
+	}
+}
@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Algorithm is a rate limiting algorithm.
+type Algorithm int
+
+const (
+	// TokenBucket allows bursts up to the limit and refills tokens continuously over the window.
+	TokenBucket Algorithm = iota
+	// SlidingWindow approximates a sliding window by weighting the previous fixed window's count.
+	SlidingWindow
+)
+
+// String implements fmt.Stringer.
+func (a Algorithm) String() string {
+	switch a {
+	case TokenBucket:
+		return "token_bucket"
+	case SlidingWindow:
+		return "sliding_window"
+	default:
+		return "unknown"
+	}
+}
+
+// Limit describes a rate limit policy.
+type Limit struct {
+	Algorithm Algorithm     // Rate limiting algorithm.
+	Requests  int           // Number of requests allowed per window.
+	Window    time.Duration // Length of the window.
+}
+
+// valid reports whether the limit allows requests over a window, so that its rate is defined.
+func (l Limit) valid() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+// Result is the outcome of a rate limit check.
+type Result struct {
+	Allowed    bool          // Whether the request is allowed.
+	Limit      int           // Number of requests allowed per window.
+	Remaining  int           // Number of requests remaining in the current window.
+	Reset      time.Duration // Time until the quota is fully restored.
+	RetryAfter time.Duration // Time until the next request would be allowed, when not allowed.
+}
+
+// bucketState is the state of a token bucket.
+type bucketState struct {
+	Tokens float64
+	Last   time.Time
+}
+
+// take consumes a token from the bucket if one is available.
+func (s *bucketState) take(limit Limit, now time.Time) Result {
+	capacity := float64(limit.Requests)
+
+	if s.Last.IsZero() {
+		s.Tokens = capacity
+	} else if elapsed := now.Sub(s.Last).Seconds(); elapsed > 0 {
+		s.Tokens = math.Min(capacity, s.Tokens+elapsed*limit.rate())
+	}
+	s.Last = now
+
+	allowed := s.Tokens >= 1
+	if allowed {
+		s.Tokens--
+	}
+
+	return s.result(limit, allowed)
+}
+
+// result returns the Result of a take that left the bucket in its current state.
+func (s *bucketState) result(limit Limit, allowed bool) Result {
+	var (
+		rate   = limit.rate()
+		result = Result{Allowed: allowed, Limit: limit.Requests}
+	)
+
+	if !allowed {
+		result.RetryAfter = seconds((1 - s.Tokens) / rate)
+	}
+
+	result.Remaining = int(s.Tokens)
+	result.Reset = seconds((float64(limit.Requests) - s.Tokens) / rate)
+	return result
+}
+
+// expired checks if the bucket is full again and can be forgotten.
+func (s *bucketState) expired(limit Limit, now time.Time) bool {
+	return now.Sub(s.Last) >= limit.Window
+}
+
+// windowState is the state of a sliding window counter.
+type windowState struct {
+	Start    time.Time
+	Current  int
+	Previous int
+}
+
+// take counts a request in the window if the weighted count is under the limit.
+func (s *windowState) take(limit Limit, now time.Time) Result {
+	windowStart := truncate(now, limit.Window)
+
+	switch {
+	case s.Start.Equal(windowStart):
+	case s.Start.Add(limit.Window).Equal(windowStart):
+		s.Previous, s.Current = s.Current, 0
+	default:
+		s.Previous, s.Current = 0, 0
+	}
+	s.Start = windowStart
+
+	allowed := s.estimate(limit, now)+1 <= float64(limit.Requests)
+	if allowed {
+		s.Current++
+	}
+
+	return s.result(limit, now, allowed)
+}
+
+// estimate returns the weighted count of requests in the sliding window ending at now.
+func (s *windowState) estimate(limit Limit, now time.Time) float64 {
+	weight := 1 - now.Sub(s.Start).Seconds()/limit.Window.Seconds()
+	return float64(s.Previous)*weight + float64(s.Current)
+}
+
+// result returns the Result of a take at the given time that left the window in its current state.
+func (s *windowState) result(limit Limit, now time.Time, allowed bool) Result {
+	var (
+		elapsed = now.Sub(s.Start)
+		result  = Result{Allowed: allowed, Limit: limit.Requests}
+	)
+
+	if !allowed {
+		result.RetryAfter = s.retryAfter(limit, elapsed)
+	}
+
+	result.Remaining = int(math.Max(0, float64(limit.Requests)-math.Ceil(s.estimate(limit, now))))
+	result.Reset = limit.Window - elapsed
+	if s.Current > 0 {
+		// Requests of the current window still weigh on the next one.
+		result.Reset += limit.Window
+	}
+	return result
+}
+
+// retryAfter returns the time until the weighted count leaves room for one more request.
+func (s *windowState) retryAfter(limit Limit, elapsed time.Duration) time.Duration {
+	var (
+		window = limit.Window.Seconds()
+		room   = float64(limit.Requests - 1 - s.Current)
+	)
+
+	// The current window alone is full, wait for the next one and its weighted count.
+	if room < 0 || s.Previous == 0 {
+		next := float64(limit.Requests-1) / float64(s.Current)
+		return seconds(window - elapsed.Seconds() + window*math.Max(0, 1-next))
+	}
+
+	// Wait until the previous window's weight has decayed enough.
+	at := window * (1 - room/float64(s.Previous))
+	return seconds(math.Max(0, at-elapsed.Seconds()))
+}
+
+// expired checks if the window no longer affects future requests.
+func (s *windowState) expired(limit Limit, now time.Time) bool {
+	return now.Sub(s.Start) >= 2*limit.Window
+}
+
+// rate returns the number of tokens refilled per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// truncate returns the start of the fixed window containing t, aligned on the Unix epoch.
+func truncate(t time.Time, window time.Duration) time.Time {
+	micros := t.UnixMicro()
+	return time.UnixMicro(micros - micros%window.Microseconds())
+}
+
+// seconds converts a number of seconds to a time.Duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	var (
+		limit = Limit{Algorithm: TokenBucket, Requests: 2, Window: 2 * time.Second}
+		now   = time.Unix(1000, 0)
+		state bucketState
+	)
+
+	got := state.take(limit, now)
+	assert.True(t, got.Allowed)
+	assert.Equal(t, 1, got.Remaining)
+
+	got = state.take(limit, now)
+	assert.True(t, got.Allowed)
+	assert.Equal(t, 0, got.Remaining)
+	assert.Equal(t, 2*time.Second, got.Reset)
+
+	got = state.take(limit, now)
+	assert.False(t, got.Allowed)
+	assert.Equal(t, time.Second, got.RetryAfter)
+
+	// One token is refilled per second.
+	got = state.take(limit, now.Add(time.Second))
+	assert.True(t, got.Allowed)
+	assert.False(t, state.expired(limit, now.Add(time.Second)))
+	assert.True(t, state.expired(limit, now.Add(4*time.Second)))
+}
+
+func TestSlidingWindow(t *testing.T) {
+	var (
+		limit = Limit{Algorithm: SlidingWindow, Requests: 2, Window: 10 * time.Second}
+		start = time.Unix(1000, 0)
+		state windowState
+	)
+
+	assert.True(t, state.take(limit, start).Allowed)
+	assert.True(t, state.take(limit, start.Add(time.Second)).Allowed)
+
+	got := state.take(limit, start.Add(2*time.Second))
+	assert.False(t, got.Allowed)
+	assert.Equal(t, 0, got.Remaining)
+	// The next window starts in 8s, and the previous count must then decay by half.
+	assert.Equal(t, 13*time.Second, got.RetryAfter)
+
+	// Halfway into the next window, the previous count weighs 1 request.
+	got = state.take(limit, start.Add(15*time.Second))
+	assert.True(t, got.Allowed)
+	assert.False(t, state.take(limit, start.Add(15*time.Second)).Allowed)
+
+	assert.True(t, state.expired(limit, start.Add(30*time.Second)))
+}
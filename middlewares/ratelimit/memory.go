@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store keeping rate limit state in process memory, limits only hold per instance.
+type MemoryStore struct {
+	mu            sync.Mutex
+	entries       map[string]*entry
+	sweepInterval time.Duration
+	nextSweep     time.Time
+}
+
+// entry is the state of a single rate limit key.
+type entry struct {
+	limit  Limit
+	bucket bucketState
+	window windowState
+}
+
+// expired checks if the entry no longer affects future requests.
+func (e *entry) expired(now time.Time) bool {
+	if e.limit.Algorithm == SlidingWindow {
+		return e.window.expired(e.limit, now)
+	}
+	return e.bucket.expired(e.limit, now)
+}
+
+// NewMemoryStore returns a new MemoryStore sweeping expired keys every sweepInterval.
+func NewMemoryStore(sweepInterval time.Duration) *MemoryStore {
+	return &MemoryStore{
+		entries:       make(map[string]*entry),
+		sweepInterval: sweepInterval,
+	}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.nextSweep) {
+		s.sweep(now)
+		s.nextSweep = now.Add(s.sweepInterval)
+	}
+
+	e, ok := s.entries[key]
+	if !ok || e.limit != limit {
+		e = &entry{limit: limit}
+		s.entries[key] = e
+	}
+
+	if limit.Algorithm == SlidingWindow {
+		return e.window.take(limit, now), nil
+	}
+	return e.bucket.take(limit, now), nil
+}
+
+// sweep removes expired keys so memory stays bounded by the number of active clients.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, e := range s.entries {
+		if e.expired(now) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// len returns the number of tracked keys.
+func (s *MemoryStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
@@ -0,0 +1,26 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreSweep(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = NewMemoryStore(time.Second)
+		limit = Limit{Algorithm: TokenBucket, Requests: 1, Window: time.Second}
+		now   = time.Unix(1000, 0)
+	)
+
+	store.Take(ctx, "a", limit, now)
+	store.Take(ctx, "b", limit, now)
+	assert.Equal(t, 2, store.len())
+
+	// Expired keys are removed on the next access after the sweep interval.
+	store.Take(ctx, "c", limit, now.Add(2*time.Second))
+	assert.Equal(t, 1, store.len())
+}
@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+)
+
+// policyKey is the attribute key for the rate limit policy.
+const policyKey = attribute.Key("ratelimit.policy")
+
+type Metrics struct {
+	throttledCounter metric.Int64Counter
+}
+
+// NewMetrics returns a new Metrics instance.
+func NewMetrics(meter *metric.Meter) *Metrics {
+	throttledCounter, _ := (*meter).Int64Counter(
+		"http_requests_throttled_total",
+		metric.WithDescription("Total number of HTTP requests rejected by rate limiting."),
+	)
+
+	return &Metrics{
+		throttledCounter: throttledCounter,
+	}
+}
+
+// IncreaseThrottledCounter increases the throttled request counter by 1.
+func (m *Metrics) IncreaseThrottledCounter(ctx context.Context, method, policy string) {
+	m.throttledCounter.Add(ctx, 1, metric.WithAttributes(semconv.HTTPMethodKey.String(method), policyKey.String(policy)))
+}
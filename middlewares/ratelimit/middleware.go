@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// defaultPolicy is the name of the policy applied to paths without a route limit.
+const defaultPolicy = "default"
+
+// Config is a struct that holds configuration options for the rate limit middleware.
+type Config struct {
+	DefaultLimit     *Limit           // Limit applied to paths without a route limit, nil means unlimited.
+	RouteLimits      map[string]Limit // Limits per path prefix, each prefix having its own quota.
+	KeyFunc          KeyFunc          // Function returning the key requests are counted on.
+	ExcludedPrefixes []string         // Path prefixes that are never rate limited.
+	Store            Store            // Storage backend, nil means a MemoryStore sweeping every SweepInterval.
+	SweepInterval    time.Duration    // Interval between removals of expired keys of the default MemoryStore.
+	FailurePolicy    FailurePolicy    // Whether requests are allowed or rejected when the Store fails.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		DefaultLimit:     nil,
+		RouteLimits:      map[string]Limit{},
+		KeyFunc:          KeyByIP,
+		ExcludedPrefixes: []string{},
+		Store:            nil,
+		SweepInterval:    time.Minute,
+		FailurePolicy:    FailOpen,
+	}
+}
+
+// limitFor returns the policy name and limit that apply to the given path, nil meaning unlimited.
+func (c *Config) limitFor(path string) (string, *Limit) {
+	if prefix, limit, ok := common.MatchPrefix(path, c.RouteLimits); ok {
+		return prefix, &limit
+	}
+	return defaultPolicy, c.DefaultLimit
+}
+
+// Middleware is the rate limit middleware function that takes a Config struct and returns the middleware.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	if config.DefaultLimit != nil && !config.DefaultLimit.valid() {
+		panic("ratelimit: default limit requires positive Requests and Window")
+	}
+	for prefix, limit := range config.RouteLimits {
+		if !limit.valid() {
+			panic("ratelimit: limit of " + prefix + " requires positive Requests and Window")
+		}
+	}
+
+	store := config.Store
+	if store == nil {
+		store = NewMemoryStore(config.SweepInterval)
+	}
+
+	var (
+		pkgName = reflect.TypeOf(struct{}{}).PkgPath()
+		meter   = otel.GetMeterProvider().Meter(pkgName)
+		metrics = NewMetrics(&meter)
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			policy, limit := config.limitFor(r.URL.Path)
+			if limit == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := config.KeyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := store.Take(r.Context(), policy+"|"+key, *limit, time.Now())
+			if err != nil {
+				slog.Error("rate limit store failed",
+					slog.String("policy", policy),
+					slog.String("error", err.Error()),
+				)
+				if config.FailurePolicy == FailClosed {
+					w.Header().Set("Retry-After", "1")
+					problem.Error(w, http.StatusServiceUnavailable, "rate limit unavailable")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setHeaders(w.Header(), *limit, result)
+
+			if !result.Allowed {
+				slog.Error("request rate limited",
+					slog.String("policy", policy),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+				)
+				metrics.IncreaseThrottledCounter(r.Context(), r.Method, policy)
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				problem.Error(w, http.StatusTooManyRequests, fmt.Sprintf("rate limit of %d requests per %s exceeded", limit.Requests, limit.Window))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setHeaders sets the RateLimit-Policy and RateLimit headers (draft-ietf-httpapi-ratelimit-headers-07).
+func setHeaders(h http.Header, limit Limit, result Result) {
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Window)))
+	h.Set("RateLimit", fmt.Sprintf("limit=%d, remaining=%d, reset=%d", result.Limit, result.Remaining, ceilSeconds(result.Reset)))
+}
+
+// ceilSeconds returns the duration rounded up to whole seconds.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyByHeader(t *testing.T) {
+	keyFunc := KeyByHeader("X-API-Key")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, keyFunc(req))
+
+	req.Header.Set("X-API-Key", "secret")
+	key := keyFunc(req)
+	assert.NotEmpty(t, key)
+	assert.NotContains(t, key, "secret")
+}
+
+func TestMiddleware(t *testing.T) {
+	config := NewConfig()
+	config.DefaultLimit = &Limit{Algorithm: TokenBucket, Requests: 2, Window: time.Minute}
+	config.RouteLimits = map[string]Limit{"/search": {Algorithm: SlidingWindow, Requests: 1, Window: time.Minute}}
+	config.ExcludedPrefixes = []string{"/health"}
+
+	var (
+		handler    = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		middleware = Middleware(config)(handler)
+	)
+
+	tests := []struct {
+		name           string
+		path           string
+		remoteAddr     string
+		wantStatus     int
+		wantRateLimit  string
+		wantRetryAfter string
+	}{
+		{"First default request", "/api", "10.0.0.1:1234", http.StatusOK, "limit=2, remaining=1, reset=30", ""},
+		{"Second default request", "/api", "10.0.0.1:1234", http.StatusOK, "limit=2, remaining=0, reset=60", ""},
+		{"Default limit exceeded", "/api", "10.0.0.1:1234", http.StatusTooManyRequests, "limit=2, remaining=0, reset=60", "30"},
+		{"Other client", "/api", "10.0.0.2:1234", http.StatusOK, "limit=2, remaining=1, reset=30", ""},
+		{"Route limit has its own quota", "/search", "10.0.0.1:1234", http.StatusOK, "", ""},
+		{"Route limit exceeded", "/search", "10.0.0.1:1234", http.StatusTooManyRequests, "", ""},
+		{"Excluded path", "/health", "10.0.0.1:1234", http.StatusOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			rr := httptest.NewRecorder()
+
+			middleware.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantRateLimit != "" {
+				assert.Equal(t, "2;w=60", rr.Header().Get("RateLimit-Policy"))
+				assert.Equal(t, tt.wantRateLimit, rr.Header().Get("RateLimit"))
+			}
+			if tt.wantRetryAfter != "" {
+				assert.Equal(t, tt.wantRetryAfter, rr.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+// failingStore is a Store that always fails.
+type failingStore struct{}
+
+// Take implements Store.
+func (failingStore) Take(context.Context, string, Limit, time.Time) (Result, error) {
+	return Result{}, errors.New("store unavailable")
+}
+
+func TestMiddlewareFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		failurePolicy FailurePolicy
+		wantStatus    int
+	}{
+		{"Fail open", FailOpen, http.StatusOK},
+		{"Fail closed", FailClosed, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.DefaultLimit = &Limit{Algorithm: TokenBucket, Requests: 1, Window: time.Second}
+			config.Store = failingStore{}
+			config.FailurePolicy = tt.failurePolicy
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
+
+func TestMiddlewareInvalidLimits(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultLimit *Limit
+		routeLimits  map[string]Limit
+	}{
+		{"Zero default requests", &Limit{Requests: 0, Window: time.Second}, nil},
+		{"Negative default window", &Limit{Requests: 1, Window: -time.Second}, nil},
+		{"Zero route window", nil, map[string]Limit{"/api": {Requests: 1}}},
+		{"Negative route requests", nil, map[string]Limit{"/api": {Requests: -1, Window: time.Second}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.DefaultLimit = tt.defaultLimit
+			config.RouteLimits = tt.routeLimits
+
+			assert.Panics(t, func() { Middleware(config) })
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript atomically refills and takes a token from a bucket stored in a hash.
+// KEYS[1] is the bucket key, ARGV holds the capacity, the window and the current time in milliseconds.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+
+if tokens == nil or last == nil then
+	tokens = capacity
+	last = now
+elseif now > last then
+	tokens = math.min(capacity, tokens + (now - last) * capacity / window)
+	last = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.17g', tokens), 'last', string.format('%.17g', last))
+redis.call('PEXPIRE', KEYS[1], math.ceil(window))
+
+return {allowed, string.format('%.17g', tokens)}
+`)
+
+// slidingWindowScript atomically counts a request in a sliding window stored in a hash.
+// KEYS[1] is the window key, ARGV holds the limit, the window and the current time in milliseconds.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local start = now - (now % window)
+
+local state = redis.call('HMGET', KEYS[1], 'start', 'current', 'previous')
+local stored = tonumber(state[1])
+local current = tonumber(state[2]) or 0
+local previous = tonumber(state[3]) or 0
+
+if stored == nil then
+	current = 0
+	previous = 0
+elseif stored + window == start then
+	previous = current
+	current = 0
+elseif stored ~= start then
+	current = 0
+	previous = 0
+end
+
+local allowed = 0
+if previous * (1 - (now - start) / window) + current + 1 <= limit then
+	current = current + 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'start', string.format('%.17g', start), 'current', current, 'previous', previous)
+redis.call('PEXPIRE', KEYS[1], math.ceil(2 * window))
+
+return {allowed, start, current, previous}
+`)
+
+// RedisStore is a Store keeping rate limit state in Redis, sharing limits between instances.
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisStore returns a new RedisStore using the given client, with keys namespaced by prefix.
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Take implements Store.
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	var (
+		keys = []string{s.prefix + key}
+		args = []any{limit.Requests, limit.Window.Milliseconds(), now.UnixMilli()}
+	)
+
+	if limit.Algorithm == SlidingWindow {
+		return s.takeWindow(ctx, keys, args, limit, now)
+	}
+	return s.takeBucket(ctx, keys, args, limit)
+}
+
+// takeBucket runs the token bucket script.
+func (s *RedisStore) takeBucket(ctx context.Context, keys []string, args []any, limit Limit) (Result, error) {
+	values, err := tokenBucketScript.Run(ctx, s.client, keys, args...).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected token bucket script reply: %v", values)
+	}
+
+	tokens, err := strconv.ParseFloat(fmt.Sprint(values[1]), 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	state := bucketState{Tokens: tokens}
+	return state.result(limit, values[0] == int64(1)), nil
+}
+
+// takeWindow runs the sliding window script.
+func (s *RedisStore) takeWindow(ctx context.Context, keys []string, args []any, limit Limit, now time.Time) (Result, error) {
+	values, err := slidingWindowScript.Run(ctx, s.client, keys, args...).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf("unexpected sliding window script reply: %v", values)
+	}
+
+	var (
+		start, _    = values[1].(int64)
+		current, _  = values[2].(int64)
+		previous, _ = values[3].(int64)
+		state       = windowState{Start: time.UnixMilli(start), Current: int(current), Previous: int(previous)}
+	)
+	return state.result(limit, now, values[0] == int64(1)), nil
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRedisStore checks that the Redis scripts agree with the in-memory algorithms.
+func TestRedisStore(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit Limit
+	}{
+		{"Token bucket", Limit{Algorithm: TokenBucket, Requests: 3, Window: 3 * time.Second}},
+		{"Sliding window", Limit{Algorithm: SlidingWindow, Requests: 3, Window: 10 * time.Second}},
+	}
+
+	offsets := []time.Duration{0, 0, 0, 0, time.Second, 1500 * time.Millisecond, 12 * time.Second, 12 * time.Second, 25 * time.Second}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				ctx    = context.Background()
+				server = miniredis.RunT(t)
+				client = redis.NewClient(&redis.Options{Addr: server.Addr()})
+				store  = NewRedisStore(client, "ratelimit:")
+				memory = NewMemoryStore(time.Minute)
+				start  = time.UnixMilli(1_700_000_000_000)
+			)
+
+			for _, offset := range offsets {
+				now := start.Add(offset)
+
+				got, err := store.Take(ctx, "key", tt.limit, now)
+				assert.NoError(t, err)
+
+				want, err := memory.Take(ctx, "key", tt.limit, now)
+				assert.NoError(t, err)
+
+				assert.Equal(t, want, got, "at offset %s", offset)
+			}
+
+			assert.True(t, server.Exists("ratelimit:key"))
+		})
+	}
+}
+
+func TestRedisStoreUnavailable(t *testing.T) {
+	var (
+		server = miniredis.RunT(t)
+		client = redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+		store  = NewRedisStore(client, "ratelimit:")
+		limit  = Limit{Algorithm: TokenBucket, Requests: 1, Window: time.Second}
+	)
+
+	server.Close()
+
+	_, err := store.Take(context.Background(), "key", limit, time.Now())
+	assert.Error(t, err)
+}
@@ -0,0 +1,22 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store is a storage backend for rate limit state.
+type Store interface {
+	// Take applies the limit to the key at the given time and returns the outcome.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// FailurePolicy decides what happens to requests when the Store cannot be reached.
+type FailurePolicy int
+
+const (
+	// FailOpen allows requests when the Store fails.
+	FailOpen FailurePolicy = iota
+	// FailClosed rejects requests when the Store fails.
+	FailClosed
+)
@@ -0,0 +1,79 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/logging"
+)
+
+// Header is the default header carrying the request ID.
+const Header = "X-Request-Id"
+
+// maxLength is the maximum length of request IDs accepted from clients.
+const maxLength = 128
+
+// Config is a struct that holds configuration options for the request ID middleware.
+type Config struct {
+	Header        string // Header carrying the request ID, in requests and responses.
+	TrustIncoming bool   // Flag to keep the request ID sent by clients or proxies instead of generating one.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		Header:        Header,
+		TrustIncoming: true,
+	}
+}
+
+type idKey struct{}
+
+// FromContext returns the ID of the request.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(idKey{}).(string)
+	return id, ok
+}
+
+// Middleware is the request ID middleware function that takes a Config struct and returns the middleware.
+// The ID is echoed in the response header, added to the request logger and available with FromContext.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(config.Header)
+			if !config.TrustIncoming || !valid(id) {
+				id = newID()
+				r.Header.Set(config.Header, id)
+			}
+			w.Header().Set(config.Header, id)
+
+			ctx := context.WithValue(r.Context(), idKey{}, id)
+			ctx = logging.WithAttrs(ctx, slog.String("requestId", id))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// newID returns a random 128-bit request ID.
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// valid checks if an incoming request ID is short and made of visible ASCII characters, so it's safe to log.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
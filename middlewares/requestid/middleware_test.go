@@ -0,0 +1,53 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		trustIncoming bool
+		incoming      string
+		wantIncoming  bool
+	}{
+		{"Generated ID", true, "", false},
+		{"Incoming ID kept", true, "abc-123", true},
+		{"Incoming ID not trusted", false, "abc-123", false},
+		{"Invalid incoming ID", true, "abc\n123", false},
+		{"Too long incoming ID", true, strings.Repeat("a", maxLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig()
+			config.TrustIncoming = tt.trustIncoming
+
+			var gotID string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotID, _ = FromContext(r.Context())
+				assert.Equal(t, gotID, r.Header.Get(Header))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(Header, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, gotID, rr.Header().Get(Header))
+			if tt.wantIncoming {
+				assert.Equal(t, tt.incoming, gotID)
+			} else {
+				assert.Len(t, gotID, 32)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package secure
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// Config is a struct that holds configuration options for the security headers middleware.
+type Config struct {
+	HSTSMaxAge                time.Duration     // Max age of the Strict-Transport-Security policy, 0 disables it.
+	HSTSIncludeSubdomains     bool              // Flag to apply the HSTS policy to subdomains.
+	HSTSPreload               bool              // Flag to allow inclusion in browsers' HSTS preload lists.
+	ContentTypeNosniff        bool              // Flag to send X-Content-Type-Options: nosniff.
+	ReferrerPolicy            string            // Referrer-Policy value, empty disables it.
+	PermissionsPolicy         string            // Permissions-Policy value, empty disables it.
+	CrossOriginOpenerPolicy   string            // Cross-Origin-Opener-Policy value, empty disables it.
+	CrossOriginEmbedderPolicy string            // Cross-Origin-Embedder-Policy value, empty disables it.
+	CrossOriginResourcePolicy string            // Cross-Origin-Resource-Policy value, empty disables it.
+	ContentSecurityPolicy     string            // Content-Security-Policy value, which may contain NoncePlaceholder.
+	RoutePolicies             map[string]string // Content-Security-Policy values per path prefix, overriding the default.
+	ReportOnly                bool              // Flag to only report Content-Security-Policy violations.
+	ExcludedPrefixes          []string          // Path prefixes that don't get the headers.
+}
+
+// NewConfig creates a new Config struct with default values.
+func NewConfig() *Config {
+	return &Config{
+		HSTSMaxAge:                365 * 24 * time.Hour,
+		HSTSIncludeSubdomains:     true,
+		HSTSPreload:               false,
+		ContentTypeNosniff:        true,
+		ReferrerPolicy:            "strict-origin-when-cross-origin",
+		PermissionsPolicy:         "camera=(), geolocation=(), microphone=()",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginEmbedderPolicy: "",
+		CrossOriginResourcePolicy: "same-origin",
+		ContentSecurityPolicy:     "default-src 'self'; script-src 'self' " + NoncePlaceholder + "; object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+		RoutePolicies:             map[string]string{},
+		ReportOnly:                false,
+		ExcludedPrefixes:          []string{},
+	}
+}
+
+// hsts returns the Strict-Transport-Security value, empty if disabled.
+func (c *Config) hsts() string {
+	if c.HSTSMaxAge <= 0 {
+		return ""
+	}
+	value := "max-age=" + strconv.FormatInt(int64(c.HSTSMaxAge/time.Second), 10)
+	if c.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if c.HSTSPreload {
+		value += "; preload"
+	}
+	return value
+}
+
+// policyFor returns the Content-Security-Policy value that applies to the given path.
+func (c *Config) policyFor(path string) string {
+	if _, policy, ok := common.MatchPrefix(path, c.RoutePolicies); ok {
+		return policy
+	}
+	return c.ContentSecurityPolicy
+}
+
+// Middleware is the security headers middleware function that takes a Config struct and returns the middleware.
+// A fresh nonce is generated for each request, available with Nonce, and substituted in the Content-Security-Policy.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	static := map[string]string{
+		"Strict-Transport-Security":    config.hsts(),
+		"Referrer-Policy":              config.ReferrerPolicy,
+		"Permissions-Policy":           config.PermissionsPolicy,
+		"Cross-Origin-Opener-Policy":   config.CrossOriginOpenerPolicy,
+		"Cross-Origin-Embedder-Policy": config.CrossOriginEmbedderPolicy,
+		"Cross-Origin-Resource-Policy": config.CrossOriginResourcePolicy,
+	}
+	if config.ContentTypeNosniff {
+		static["X-Content-Type-Options"] = "nosniff"
+	}
+
+	cspHeader := "Content-Security-Policy"
+	if config.ReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for name, value := range static {
+				if value != "" {
+					w.Header().Set(name, value)
+				}
+			}
+
+			if policy := config.policyFor(r.URL.Path); policy != "" {
+				if strings.Contains(policy, NoncePlaceholder) {
+					nonce, err := newNonce()
+					if err != nil {
+						slog.Error("failed to generate CSP nonce", slog.String("error", err.Error()))
+						problem.Error(w, http.StatusInternalServerError, "failed to generate CSP nonce")
+						return
+					}
+					policy = withNonce(policy, nonce)
+					r = r.WithContext(context.WithValue(r.Context(), nonceKey{}, &requestNonce{value: nonce}))
+				}
+				w.Header().Set(cspHeader, policy)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
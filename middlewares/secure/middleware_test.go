@@ -0,0 +1,98 @@
+package secure
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	config := NewConfig()
+	config.RoutePolicies = map[string]string{"/docs": "default-src 'self' cdn.example.com"}
+	config.ExcludedPrefixes = []string{"/metrics"}
+
+	tests := []struct {
+		name       string
+		target     string
+		wantHeader bool
+		wantCSP    string
+	}{
+		{"Default policy", "/", true, "default-src 'self'; script-src 'self' 'nonce-"},
+		{"Route policy", "/docs/api", true, "default-src 'self' cdn.example.com"},
+		{"Excluded path", "/metrics", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			rr := httptest.NewRecorder()
+
+			Middleware(config)(handler).ServeHTTP(rr, req)
+
+			if !tt.wantHeader {
+				assert.Empty(t, rr.Header())
+				return
+			}
+			assert.Equal(t, "max-age=31536000; includeSubDomains", rr.Header().Get("Strict-Transport-Security"))
+			assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+			assert.Equal(t, "strict-origin-when-cross-origin", rr.Header().Get("Referrer-Policy"))
+			assert.Equal(t, "camera=(), geolocation=(), microphone=()", rr.Header().Get("Permissions-Policy"))
+			assert.Equal(t, "same-origin", rr.Header().Get("Cross-Origin-Opener-Policy"))
+			assert.Equal(t, "same-origin", rr.Header().Get("Cross-Origin-Resource-Policy"))
+			assert.Empty(t, rr.Header().Get("Cross-Origin-Embedder-Policy"))
+			assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Security-Policy"), tt.wantCSP))
+		})
+	}
+}
+
+func TestMiddlewareNonce(t *testing.T) {
+	config := NewConfig()
+	tmpl := template.Must(template.New("page").Parse(`<script nonce="{{.Nonce}}">init()</script>`))
+
+	var nonces []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.False(t, NonceRead(r.Context()))
+		nonce := Nonce(r.Context())
+		assert.True(t, NonceRead(r.Context()))
+		nonces = append(nonces, nonce)
+		require.NoError(t, tmpl.Execute(w, struct{ Nonce string }{nonce}))
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rr := httptest.NewRecorder()
+
+		Middleware(config)(handler).ServeHTTP(rr, req)
+
+		nonce := nonces[i]
+		assert.NotEmpty(t, nonce)
+		assert.Contains(t, rr.Header().Get("Content-Security-Policy"), "'nonce-"+nonce+"'")
+		assert.Equal(t, `<script nonce="`+nonce+`">init()</script>`, rr.Body.String())
+	}
+	assert.NotEqual(t, nonces[0], nonces[1])
+}
+
+func TestMiddlewareReportOnly(t *testing.T) {
+	config := NewConfig()
+	config.ContentSecurityPolicy = "default-src 'self'; report-uri /csp-reports"
+	config.ReportOnly = true
+	config.HSTSMaxAge = 0
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rr := httptest.NewRecorder()
+
+	Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, Nonce(r.Context()))
+	})).ServeHTTP(rr, req)
+
+	assert.Empty(t, rr.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "default-src 'self'; report-uri /csp-reports", rr.Header().Get("Content-Security-Policy-Report-Only"))
+	assert.Empty(t, rr.Header().Get("Strict-Transport-Security"))
+}
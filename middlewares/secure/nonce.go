@@ -0,0 +1,53 @@
+package secure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"sync/atomic"
+)
+
+// NoncePlaceholder is replaced in Content-Security-Policy values by the nonce source of the request,
+// for instance "script-src 'self' {nonce}" becomes "script-src 'self' 'nonce-...'".
+const NoncePlaceholder = "{nonce}"
+
+type nonceKey struct{}
+
+// requestNonce is the CSP nonce of a request, recording whether it was read.
+type requestNonce struct {
+	value string
+	read  atomic.Bool
+}
+
+// Nonce returns the CSP nonce of the request, to set on inline <script> and <style> elements,
+// typically passed to html/template as data: <script nonce="{{.Nonce}}">.
+func Nonce(ctx context.Context) string {
+	nonce, ok := ctx.Value(nonceKey{}).(*requestNonce)
+	if !ok {
+		return ""
+	}
+	nonce.read.Store(true)
+	return nonce.value
+}
+
+// NonceRead checks if the CSP nonce of the request was read with Nonce, in which case the response likely embeds it
+// and must not be reused for other requests.
+func NonceRead(ctx context.Context) bool {
+	nonce, ok := ctx.Value(nonceKey{}).(*requestNonce)
+	return ok && nonce.read.Load()
+}
+
+// newNonce returns a random nonce of 128 bits, base64url encoded so html/template doesn't escape it.
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// withNonce replaces the nonce placeholder in the policy with the nonce source.
+func withNonce(policy, nonce string) string {
+	return strings.ReplaceAll(policy, NoncePlaceholder, "'nonce-"+nonce+"'")
+}
@@ -0,0 +1,71 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidCookie is returned when a cookie can't be authenticated with any key.
+var ErrInvalidCookie = errors.New("invalid session cookie")
+
+// codec encrypts and authenticates cookie values with AES-GCM. The first key encrypts,
+// while every key decrypts, so keys can be rotated without invalidating existing sessions.
+type codec struct {
+	aeads []cipher.AEAD
+}
+
+// newCodec returns a new codec for the given AES keys of 16, 24 or 32 bytes.
+func newCodec(keys [][]byte) (*codec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one session key is required")
+	}
+
+	c := &codec{aeads: make([]cipher.AEAD, 0, len(keys))}
+	for i, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("session key %d: %w", i, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("session key %d: %w", i, err)
+		}
+		c.aeads = append(c.aeads, aead)
+	}
+	return c, nil
+}
+
+// encode encrypts the plaintext with the first key, binding it to the cookie name.
+func (c *codec) encode(name string, plaintext []byte) (string, error) {
+	aead := c.aeads[0]
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// decode authenticates and decrypts the value with any of the keys.
+func (c *codec) decode(name, value string) ([]byte, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+
+	for _, aead := range c.aeads {
+		if len(sealed) < aead.NonceSize() {
+			continue
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		if plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name)); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, ErrInvalidCookie
+}
@@ -0,0 +1,58 @@
+package session
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec(t *testing.T) {
+	var (
+		oldKey = bytes.Repeat([]byte{1}, 32)
+		newKey = bytes.Repeat([]byte{2}, 32)
+	)
+	previous, err := newCodec([][]byte{oldKey})
+	require.NoError(t, err)
+	rotated, err := newCodec([][]byte{newKey, oldKey})
+	require.NoError(t, err)
+
+	oldValue, err := previous.encode("session", []byte("data"))
+	require.NoError(t, err)
+	newValue, err := rotated.encode("session", []byte("data"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		codec   *codec
+		cookie  string
+		value   string
+		want    []byte
+		wantErr error
+	}{
+		{"Current key", rotated, "session", newValue, []byte("data"), nil},
+		{"Rotated key", rotated, "session", oldValue, []byte("data"), nil},
+		{"Removed key", previous, "session", newValue, nil, ErrInvalidCookie},
+		{"Other cookie name", rotated, "other", newValue, nil, ErrInvalidCookie},
+		{"Tampered value", rotated, "session", newValue[:len(newValue)-2] + "AA", nil, ErrInvalidCookie},
+		{"Truncated value", rotated, "session", "AAAA", nil, ErrInvalidCookie},
+		{"Not base64", rotated, "session", "not base64!", nil, ErrInvalidCookie},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.codec.decode(tt.cookie, tt.value)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewCodecInvalidKeys(t *testing.T) {
+	_, err := newCodec(nil)
+	assert.Error(t, err)
+
+	_, err = newCodec([][]byte{[]byte("short")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,298 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+const (
+	// chunkSize is the maximum length of a cookie value, keeping cookies under the 4096 bytes browsers accept.
+	chunkSize = 3800
+	// maxChunks is the maximum number of cookies a session is split into.
+	maxChunks = 8
+)
+
+// Config is a struct that holds configuration options for the session middleware.
+type Config struct {
+	Keys             [][]byte      // AES keys of 16, 24 or 32 bytes, the first encrypts and all decrypt.
+	Store            Store         // Server-side storage backend, nil keeps the data in the cookie.
+	CookieName       string        // Name of the session cookie, the "__Host-" prefix requires CookieSecure.
+	CookieSecure     bool          // Flag to only send the session cookie over HTTPS.
+	SameSite         http.SameSite // SameSite attribute of the session cookie.
+	IdleTimeout      time.Duration // Inactivity duration after which sessions expire, extended by each request.
+	AbsoluteTimeout  time.Duration // Duration after which sessions expire regardless of activity.
+	TouchInterval    time.Duration // Minimum interval between expiration extensions of unmodified sessions.
+	ExcludedPrefixes []string      // Path prefixes that don't load sessions.
+}
+
+// NewConfig creates a new Config struct with default values.
+// The Keys must be set, for instance with 32 random bytes.
+func NewConfig() *Config {
+	return &Config{
+		Keys:             nil,
+		Store:            nil,
+		CookieName:       "__Host-session",
+		CookieSecure:     true,
+		SameSite:         http.SameSiteLaxMode,
+		IdleTimeout:      30 * time.Minute,
+		AbsoluteTimeout:  24 * time.Hour,
+		TouchInterval:    time.Minute,
+		ExcludedPrefixes: []string{},
+	}
+}
+
+// payload is the content of the session cookie. Values are omitted when a Store is used.
+type payload struct {
+	ID        string                     `json:"id"`
+	Values    map[string]json.RawMessage `json:"values,omitempty"`
+	CreatedAt int64                      `json:"createdAt"`
+	LastSeen  int64                      `json:"lastSeen"`
+}
+
+// Middleware is the session middleware function that takes a Config struct and returns the middleware.
+// The session is available with FromContext, Get and Set, and saved before the response headers are written.
+// It panics if the keys are invalid.
+func Middleware(config *Config) func(http.Handler) http.Handler {
+	codec, err := newCodec(config.Keys)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if common.ShouldSkip(r.URL.Path, config.ExcludedPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			now := time.Now()
+			s, chunks, err := load(r, config, codec, now)
+			if err != nil {
+				slog.Error("failed to load session", slog.String("error", err.Error()))
+				problem.Error(w, http.StatusServiceUnavailable, "session storage unavailable")
+				return
+			}
+
+			sw := &sessionWriter{ResponseWriter: w}
+			sw.commit = func() {
+				if err := save(r.Context(), sw.ResponseWriter, config, codec, s, chunks, time.Now()); err != nil {
+					slog.Error("failed to save session", slog.String("error", err.Error()))
+				}
+			}
+
+			next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), sessionKey{}, s)))
+			sw.commitOnce()
+		})
+	}
+}
+
+// load returns the session of the request, or a new one if it is missing, invalid or expired,
+// with the number of cookies it was read from.
+func load(r *http.Request, config *Config, codec *codec, now time.Time) (*Session, int, error) {
+	value, chunks := readCookies(r, config.CookieName)
+	if value == "" {
+		s, err := newSession(now)
+		return s, chunks, err
+	}
+
+	var p payload
+	plaintext, err := codec.decode(config.CookieName, value)
+	if err == nil {
+		err = json.Unmarshal(plaintext, &p)
+	}
+	createdAt, lastSeen := time.Unix(p.CreatedAt, 0), time.Unix(p.LastSeen, 0)
+	if err != nil || now.Sub(lastSeen) > config.IdleTimeout || now.Sub(createdAt) > config.AbsoluteTimeout {
+		s, err := newSession(now)
+		return s, chunks, err
+	}
+
+	if config.Store != nil {
+		data, err := config.Store.Load(r.Context(), p.ID)
+		if errors.Is(err, ErrNotFound) {
+			s, err := newSession(now)
+			return s, chunks, err
+		}
+		if err != nil {
+			return nil, chunks, err
+		}
+		if err := json.Unmarshal(data, &p.Values); err != nil {
+			return nil, chunks, err
+		}
+	}
+	if p.Values == nil {
+		p.Values = map[string]json.RawMessage{}
+	}
+
+	return &Session{
+		id:        p.ID,
+		values:    p.Values,
+		createdAt: createdAt,
+		lastSeen:  lastSeen,
+	}, chunks, nil
+}
+
+// save writes the session cookies when the session was modified, or its expiration must be extended.
+func save(ctx context.Context, w http.ResponseWriter, config *Config, codec *codec, s *Session, chunks int, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.destroyed {
+		expireCookies(w, config, 0, chunks)
+		if config.Store != nil && !s.isNew {
+			return errors.Join(config.Store.Delete(ctx, s.id), deletePrevious(ctx, config, s))
+		}
+		return nil
+	}
+	if !s.modified && (s.isNew || now.Sub(s.lastSeen) < config.TouchInterval) {
+		return nil
+	}
+
+	s.lastSeen = now
+	expiresAt := s.lastSeen.Add(config.IdleTimeout)
+	if absolute := s.createdAt.Add(config.AbsoluteTimeout); absolute.Before(expiresAt) {
+		expiresAt = absolute
+	}
+
+	p := payload{ID: s.id, CreatedAt: s.createdAt.Unix(), LastSeen: s.lastSeen.Unix()}
+	if config.Store != nil {
+		data, err := json.Marshal(s.values)
+		if err != nil {
+			return err
+		}
+		if err := config.Store.Save(ctx, s.id, data, expiresAt); err != nil {
+			return err
+		}
+		if err := deletePrevious(ctx, config, s); err != nil {
+			return err
+		}
+	} else {
+		p.Values = s.values
+	}
+
+	plaintext, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	value, err := codec.encode(config.CookieName, plaintext)
+	if err != nil {
+		return err
+	}
+	if len(value) > chunkSize*maxChunks {
+		return fmt.Errorf("session of %d bytes exceeds %d cookies", len(value), maxChunks)
+	}
+
+	written := 0
+	for ; len(value) > 0; written++ {
+		n := chunkSize
+		if n > len(value) {
+			n = len(value)
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     chunkName(config.CookieName, written),
+			Value:    value[:n],
+			Path:     "/",
+			Expires:  expiresAt,
+			MaxAge:   int(expiresAt.Sub(now) / time.Second),
+			Secure:   config.CookieSecure,
+			HttpOnly: true,
+			SameSite: config.SameSite,
+		})
+		value = value[n:]
+	}
+	expireCookies(w, config, written, chunks)
+
+	s.isNew, s.modified, s.previous = false, false, ""
+	return nil
+}
+
+// deletePrevious deletes the session replaced by Regenerate from the store.
+func deletePrevious(ctx context.Context, config *Config, s *Session) error {
+	if s.previous == "" {
+		return nil
+	}
+	return config.Store.Delete(ctx, s.previous)
+}
+
+// readCookies returns the session cookie value joined from its chunks, and the number of chunks.
+func readCookies(r *http.Request, name string) (string, int) {
+	var value strings.Builder
+	for i := 0; i < maxChunks; i++ {
+		cookie, err := r.Cookie(chunkName(name, i))
+		if err != nil {
+			return value.String(), i
+		}
+		value.WriteString(cookie.Value)
+	}
+	return value.String(), maxChunks
+}
+
+// expireCookies removes the chunks from index from up to index to, left over from a larger session.
+func expireCookies(w http.ResponseWriter, config *Config, from, to int) {
+	for i := from; i < to; i++ {
+		http.SetCookie(w, &http.Cookie{
+			Name:     chunkName(config.CookieName, i),
+			Path:     "/",
+			MaxAge:   -1,
+			Secure:   config.CookieSecure,
+			HttpOnly: true,
+			SameSite: config.SameSite,
+		})
+	}
+}
+
+// chunkName returns the name of the cookie holding the chunk at index i.
+func chunkName(name string, i int) string {
+	if i == 0 {
+		return name
+	}
+	return name + "." + strconv.Itoa(i)
+}
+
+// sessionWriter is a http.ResponseWriter saving the session before the headers are written.
+type sessionWriter struct {
+	http.ResponseWriter
+	commit func()
+	once   sync.Once
+}
+
+// commitOnce saves the session unless it was already saved.
+func (w *sessionWriter) commitOnce() {
+	w.once.Do(w.commit)
+}
+
+// WriteHeader implements http.ResponseWriter and saves the session first.
+func (w *sessionWriter) WriteHeader(statusCode int) {
+	w.commitOnce()
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter and saves the session first.
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.commitOnce()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher and saves the session first.
+func (w *sessionWriter) Flush() {
+	w.commitOnce()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,227 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTrip serves a request carrying the cookies and returns the response cookies merged into them.
+func roundTrip(t *testing.T, handler http.Handler, target string, cookies map[string]*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	for _, cookie := range rr.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(cookies, cookie.Name)
+			continue
+		}
+		cookies[cookie.Name] = cookie
+	}
+	return rr
+}
+
+func newTestConfig() *Config {
+	config := NewConfig()
+	config.Keys = [][]byte{bytes.Repeat([]byte{1}, 32)}
+	return config
+}
+
+func TestMiddleware(t *testing.T) {
+	for name, store := range map[string]Store{"Cookie": nil, "Memory": NewMemoryStore(time.Minute)} {
+		t.Run(name, func(t *testing.T) {
+			config := newTestConfig()
+			config.Store = store
+
+			var ids []string
+			handler := Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				s, ok := FromContext(r.Context())
+				require.True(t, ok)
+
+				switch r.URL.Path {
+				case "/login":
+					require.NoError(t, s.Regenerate())
+					require.NoError(t, Set(r.Context(), "user", "alice"))
+				case "/logout":
+					s.Destroy()
+				}
+				ids = append(ids, s.ID())
+
+				user, _ := Get[string](r.Context(), "user")
+				_, _ = w.Write([]byte(user))
+			}))
+
+			cookies := map[string]*http.Cookie{}
+
+			rr := roundTrip(t, handler, "/", cookies)
+			assert.Empty(t, cookies, "unmodified new sessions aren't saved")
+
+			rr = roundTrip(t, handler, "/login", cookies)
+			assert.Equal(t, "alice", rr.Body.String())
+			require.Contains(t, cookies, "__Host-session")
+			assert.True(t, cookies["__Host-session"].HttpOnly)
+			assert.True(t, cookies["__Host-session"].Secure)
+			assert.Equal(t, 1800, cookies["__Host-session"].MaxAge)
+
+			rr = roundTrip(t, handler, "/", cookies)
+			assert.Equal(t, "alice", rr.Body.String())
+			assert.Equal(t, ids[1], ids[2])
+			assert.NotEqual(t, ids[0], ids[1], "login regenerates the session ID")
+
+			rr = roundTrip(t, handler, "/logout", cookies)
+			assert.Empty(t, cookies)
+
+			if store != nil {
+				_, err := store.Load(context.Background(), ids[1])
+				assert.ErrorIs(t, err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRegenerateDeletesPrevious(t *testing.T) {
+	config := newTestConfig()
+	config.Store = NewMemoryStore(time.Minute)
+
+	handler := Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := FromContext(r.Context())
+		if r.URL.Path == "/login" {
+			require.NoError(t, s.Regenerate())
+		}
+		require.NoError(t, s.Set("visited", true))
+	}))
+
+	cookies := map[string]*http.Cookie{}
+	roundTrip(t, handler, "/", cookies)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies["__Host-session"])
+	var before string
+	Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		before, _ = SessionID(r)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	require.NotEmpty(t, before)
+
+	roundTrip(t, handler, "/login", cookies)
+
+	_, err := config.Store.Load(context.Background(), before)
+	assert.ErrorIs(t, err, ErrNotFound, "the fixated session ID is no longer valid")
+}
+
+func TestMiddlewareChunking(t *testing.T) {
+	config := newTestConfig()
+	large := strings.Repeat("x", 2*chunkSize)
+
+	handler := Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			require.NoError(t, Set(r.Context(), "data", large))
+		case "/small":
+			require.NoError(t, Set(r.Context(), "data", "small"))
+		}
+		data, _ := Get[string](r.Context(), "data")
+		_, _ = w.Write([]byte(data))
+	}))
+
+	cookies := map[string]*http.Cookie{}
+	roundTrip(t, handler, "/large", cookies)
+	assert.Len(t, cookies, 3)
+	for _, cookie := range cookies {
+		assert.LessOrEqual(t, len(cookie.Value), chunkSize)
+	}
+
+	rr := roundTrip(t, handler, "/", cookies)
+	assert.Equal(t, large, rr.Body.String())
+
+	// Shrinking the session removes the leftover chunks.
+	roundTrip(t, handler, "/small", cookies)
+	assert.Len(t, cookies, 1)
+	rr = roundTrip(t, handler, "/", cookies)
+	assert.Equal(t, "small", rr.Body.String())
+}
+
+func TestMiddlewareExpiration(t *testing.T) {
+	config := newTestConfig()
+	codec, err := newCodec(config.Keys)
+	require.NoError(t, err)
+
+	now := time.Now()
+	tests := []struct {
+		name       string
+		createdAt  time.Time
+		lastSeen   time.Time
+		wantUser   string
+		wantCookie bool
+	}{
+		{"Active session", now.Add(-time.Hour), now.Add(-10 * time.Second), "alice", false},
+		{"Sliding expiration", now.Add(-time.Hour), now.Add(-10 * time.Minute), "alice", true},
+		{"Idle session", now.Add(-time.Hour), now.Add(-time.Hour), "", false},
+		{"Absolute expiration", now.Add(-25 * time.Hour), now.Add(-10 * time.Second), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext := []byte(`{"id":"s1","values":{"user":"alice"},"createdAt":` +
+				itoa(tt.createdAt.Unix()) + `,"lastSeen":` + itoa(tt.lastSeen.Unix()) + `}`)
+			value, err := codec.encode(config.CookieName, plaintext)
+			require.NoError(t, err)
+
+			var user string
+			handler := Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, _ = Get[string](r.Context(), "user")
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.AddCookie(&http.Cookie{Name: config.CookieName, Value: value})
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantUser, user)
+			assert.Equal(t, tt.wantCookie, len(rr.Result().Cookies()) > 0)
+		})
+	}
+}
+
+func TestMiddlewareStoreUnavailable(t *testing.T) {
+	config := newTestConfig()
+	config.Store = failingStore{}
+	codec, err := newCodec(config.Keys)
+	require.NoError(t, err)
+
+	now := time.Now().Unix()
+	value, err := codec.encode(config.CookieName, []byte(`{"id":"s1","createdAt":`+itoa(now)+`,"lastSeen":`+itoa(now)+`}`))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: config.CookieName, Value: value})
+	rr := httptest.NewRecorder()
+	Middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+type failingStore struct{}
+
+func (failingStore) Load(context.Context, string) ([]byte, error) { return nil, assert.AnError }
+
+func (failingStore) Save(context.Context, string, []byte, time.Time) error { return assert.AnError }
+
+func (failingStore) Delete(context.Context, string) error { return assert.AnError }
+
+func itoa(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
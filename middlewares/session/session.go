@@ -0,0 +1,165 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Session is the session of a request. It is safe for concurrent use.
+type Session struct {
+	mu        sync.Mutex
+	id        string
+	previous  string // ID replaced by Regenerate, to delete from the store.
+	values    map[string]json.RawMessage
+	createdAt time.Time
+	lastSeen  time.Time
+	isNew     bool
+	modified  bool
+	destroyed bool
+}
+
+// newSession returns a new empty session.
+func newSession(now time.Time) (*Session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		id:        id,
+		values:    map[string]json.RawMessage{},
+		createdAt: now,
+		lastSeen:  now,
+		isNew:     true,
+	}, nil
+}
+
+// newID returns a random session ID of 256 bits.
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ID returns the session ID.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// IsNew checks if the session was created by this request.
+func (s *Session) IsNew() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isNew
+}
+
+// Get decodes the value stored under key into dest, reporting whether it was found.
+func (s *Session) Get(key string, dest any) (bool, error) {
+	s.mu.Lock()
+	raw, ok := s.values[key]
+	s.mu.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, dest)
+}
+
+// Set stores the JSON encoding of value under key.
+func (s *Session) Set(key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = raw
+	s.modified = true
+	return nil
+}
+
+// Delete removes the value stored under key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+// Regenerate gives the session a new ID while keeping its values. It must be called when the privilege level
+// changes, such as on login, to prevent session fixation.
+func (s *Session) Regenerate() error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.previous == "" && !s.isNew {
+		s.previous = s.id
+	}
+	s.id = id
+	s.createdAt = time.Now()
+	s.modified = true
+	return nil
+}
+
+// Destroy removes every value and expires the session cookie, such as on logout.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = map[string]json.RawMessage{}
+	s.destroyed = true
+	s.modified = true
+}
+
+type sessionKey struct{}
+
+// FromContext returns the session of the request.
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*Session)
+	return s, ok
+}
+
+// Get returns the value of type T stored under key in the session of the request.
+func Get[T any](ctx context.Context, key string) (T, bool) {
+	var value T
+
+	s, ok := FromContext(ctx)
+	if !ok {
+		return value, false
+	}
+	found, err := s.Get(key, &value)
+	return value, found && err == nil
+}
+
+// Set stores the value under key in the session of the request.
+func Set[T any](ctx context.Context, key string, value T) error {
+	s, ok := FromContext(ctx)
+	if !ok {
+		return ErrNoSession
+	}
+	return s.Set(key, value)
+}
+
+// SessionID returns the session ID of the request, such as for csrf.Config.SessionID.
+// Sessions created by the request are not reported, since the client doesn't hold their ID yet.
+func SessionID(r *http.Request) (string, bool) {
+	s, ok := FromContext(r.Context())
+	if !ok || s.IsNew() {
+		return "", false
+	}
+	return s.ID(), true
+}
@@ -0,0 +1,204 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned by stores when a session doesn't exist or expired.
+	ErrNotFound = errors.New("session not found")
+	// ErrNoSession is returned when the request has no session, as the middleware is missing.
+	ErrNoSession = errors.New("no session in context")
+)
+
+// Store is an interface for server-side session storage backends. When a Store is used,
+// the cookie only carries the session ID.
+type Store interface {
+	// Load returns the data of the session, or ErrNotFound.
+	Load(ctx context.Context, id string) ([]byte, error)
+	// Save stores the data of the session until expiresAt.
+	Save(ctx context.Context, id string, data []byte, expiresAt time.Time) error
+	// Delete removes the session.
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryStore is a Store keeping sessions in process memory, sessions are lost on restart.
+type MemoryStore struct {
+	mu            sync.Mutex
+	entries       map[string]memoryEntry
+	sweepInterval time.Duration
+	nextSweep     time.Time
+}
+
+// memoryEntry is a session stored by MemoryStore.
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// NewMemoryStore returns a new MemoryStore sweeping expired sessions every sweepInterval.
+func NewMemoryStore(sweepInterval time.Duration) *MemoryStore {
+	return &MemoryStore{
+		entries:       make(map[string]memoryEntry),
+		sweepInterval: sweepInterval,
+	}
+}
+
+// Load implements Store.
+func (s *MemoryStore) Load(_ context.Context, id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, ErrNotFound
+	}
+	return e.data, nil
+}
+
+// Save implements Store.
+func (s *MemoryStore) Save(_ context.Context, id string, data []byte, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for key, e := range s.entries {
+			if now.After(e.expiresAt) {
+				delete(s.entries, key)
+			}
+		}
+		s.nextSweep = now.Add(s.sweepInterval)
+	}
+
+	s.entries[id] = memoryEntry{data: data, expiresAt: expiresAt}
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+	return nil
+}
+
+// FileStore is a Store keeping each session in a file of a directory, which can be shared between instances.
+type FileStore struct {
+	dir           string
+	sweepInterval time.Duration
+
+	mu        sync.Mutex
+	nextSweep time.Time
+}
+
+// fileEntry is the content of a session file.
+type fileEntry struct {
+	Data      []byte    `json:"data"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// NewFileStore returns a new FileStore keeping sessions in dir, created if missing,
+// and sweeping expired sessions every sweepInterval.
+func NewFileStore(dir string, sweepInterval time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, sweepInterval: sweepInterval}, nil
+}
+
+// Load implements Store.
+func (s *FileStore) Load(_ context.Context, id string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var e fileEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	if time.Now().After(e.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return e.Data, nil
+}
+
+// Save implements Store.
+func (s *FileStore) Save(_ context.Context, id string, data []byte, expiresAt time.Time) error {
+	s.sweep()
+
+	content, err := json.Marshal(fileEntry{Data: data, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so concurrent loads never see a partial session.
+	tmp, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(id))
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the file of the session, named after the hashed ID so IDs never reach the filesystem.
+func (s *FileStore) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// sweep removes expired session files, at most once per sweep interval.
+func (s *FileStore) sweep() {
+	s.mu.Lock()
+	now := time.Now()
+	if now.Before(s.nextSweep) {
+		s.mu.Unlock()
+		return
+	}
+	s.nextSweep = now.Add(s.sweepInterval)
+	s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(s.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var e fileEntry
+		if json.Unmarshal(data, &e) == nil && now.After(e.ExpiresAt) {
+			_ = os.Remove(path)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	fileStore, err := NewFileStore(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	stores := map[string]Store{
+		"Memory": NewMemoryStore(0),
+		"File":   fileStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			_, err := store.Load(ctx, "missing")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			require.NoError(t, store.Save(ctx, "s1", []byte(`{"user":"alice"}`), time.Now().Add(time.Hour)))
+			data, err := store.Load(ctx, "s1")
+			require.NoError(t, err)
+			assert.Equal(t, []byte(`{"user":"alice"}`), data)
+
+			require.NoError(t, store.Save(ctx, "expired", []byte(`{}`), time.Now().Add(-time.Second)))
+			_, err = store.Load(ctx, "expired")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			require.NoError(t, store.Delete(ctx, "s1"))
+			require.NoError(t, store.Delete(ctx, "s1"))
+			_, err = store.Load(ctx, "s1")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
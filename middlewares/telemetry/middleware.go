@@ -6,16 +6,22 @@ import (
 	"time"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/forwarded"
 )
 
+// clientAddressKey is the attribute key for the client IP address, as resolved behind trusted proxies.
+const clientAddressKey = attribute.Key("client.address")
+
 type MiddlewareOption func(*middlewareOptions)
 
 type middlewareOptions struct {
-	excludedPrefixes []string
+	excludedPrefixes    []string
+	redactedQueryParams []string
 }
 
 // WithExcludedPrefixes sets the excluded paths for the middleware.
@@ -25,6 +31,14 @@ func WithExcludedPrefixes(prefixes []string) MiddlewareOption {
 	}
 }
 
+// WithRedactedQueryParams sets query parameters whose values are redacted from the URL, on top of
+// common.SensitiveQueryParams.
+func WithRedactedQueryParams(params []string) MiddlewareOption {
+	return func(options *middlewareOptions) {
+		options.redactedQueryParams = params
+	}
+}
+
 // Middleware is a simple OpenTelemetry HTTP middleware.
 func Middleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
 	var options middlewareOptions
@@ -50,8 +64,9 @@ func Middleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
 
 		span.SetAttributes(
 			semconv.HTTPMethodKey.String(r.Method),
-			semconv.HTTPURLKey.String(r.URL.String()),
+			semconv.HTTPURLKey.String(common.RedactedURL(r.URL, options.redactedQueryParams)),
 			semconv.HTTPUserAgentKey.String(r.UserAgent()),
+			clientAddressKey.String(forwarded.ClientAddress(r)),
 		)
 
 		startTime := time.Now()
@@ -81,3 +96,8 @@ func (w *statusWriter) WriteHeader(statusCode int) {
 	w.statusCode = statusCode
 	w.ResponseWriter.WriteHeader(statusCode)
 }
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
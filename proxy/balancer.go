@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"hash/fnv"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/2n3g5c9/go-http/middlewares/forwarded"
+)
+
+// Balancer picks the upstream of a request among the available ones, never empty.
+type Balancer interface {
+	Pick(r *http.Request, upstreams []*Upstream) *Upstream
+}
+
+// KeyFunc returns the key requests are hashed on by ConsistentHash.
+type KeyFunc func(r *http.Request) string
+
+// ByClientIP is a KeyFunc hashing on the client IP address, as resolved behind trusted proxies.
+func ByClientIP(r *http.Request) string {
+	return forwarded.ClientAddress(r)
+}
+
+// ByHeader returns a KeyFunc hashing on the value of a request header, such as a tenant or session ID.
+func ByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// RoundRobin returns a Balancer cycling through the upstreams.
+func RoundRobin() Balancer {
+	return &roundRobin{}
+}
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+func (b *roundRobin) Pick(_ *http.Request, upstreams []*Upstream) *Upstream {
+	return upstreams[(b.next.Add(1)-1)%uint64(len(upstreams))]
+}
+
+// LeastConnections returns a Balancer picking the upstream with the fewest requests in flight, cycling through
+// the ones with as few.
+func LeastConnections() Balancer {
+	return &leastConnections{}
+}
+
+type leastConnections struct {
+	next atomic.Uint64
+}
+
+func (b *leastConnections) Pick(_ *http.Request, upstreams []*Upstream) *Upstream {
+	offset := b.next.Add(1) - 1
+
+	var best *Upstream
+	for i := range upstreams {
+		u := upstreams[(offset+uint64(i))%uint64(len(upstreams))]
+		if best == nil || u.Active() < best.Active() {
+			best = u
+		}
+	}
+	return best
+}
+
+// ConsistentHash returns a Balancer sending requests with the same key to the same upstream, with rendezvous
+// hashing so that only the requests of an upstream leaving or joining the available ones move.
+// Requests without a key are balanced round-robin.
+func ConsistentHash(key KeyFunc) Balancer {
+	return &consistentHash{key: key, fallback: &roundRobin{}}
+}
+
+type consistentHash struct {
+	key      KeyFunc
+	fallback *roundRobin
+}
+
+func (b *consistentHash) Pick(r *http.Request, upstreams []*Upstream) *Upstream {
+	key := b.key(r)
+	if key == "" {
+		return b.fallback.Pick(r, upstreams)
+	}
+
+	var (
+		best      *Upstream
+		bestScore uint64
+	)
+	for _, u := range upstreams {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(u.URL.String()))
+		if score := h.Sum64(); best == nil || score > bestScore {
+			best, bestScore = u, score
+		}
+	}
+	return best
+}
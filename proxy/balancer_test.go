@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testUpstreams(t *testing.T, targets ...string) []*Upstream {
+	t.Helper()
+	upstreams := make([]*Upstream, len(targets))
+	for i, target := range targets {
+		u, err := url.Parse(target)
+		assert.NoError(t, err)
+		upstreams[i] = newUpstream(u)
+	}
+	return upstreams
+}
+
+func TestRoundRobin(t *testing.T) {
+	upstreams := testUpstreams(t, "http://a", "http://b", "http://c")
+	balancer := RoundRobin()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, balancer.Pick(req, upstreams).URL.Host)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a"}, got)
+}
+
+func TestLeastConnections(t *testing.T) {
+	upstreams := testUpstreams(t, "http://a", "http://b", "http://c")
+	upstreams[0].active.Store(2)
+	upstreams[2].active.Store(1)
+	balancer := LeastConnections()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	assert.Equal(t, "b", balancer.Pick(req, upstreams).URL.Host)
+	upstreams[1].active.Store(3)
+	assert.Equal(t, "c", balancer.Pick(req, upstreams).URL.Host)
+}
+
+func TestConsistentHash(t *testing.T) {
+	upstreams := testUpstreams(t, "http://a", "http://b", "http://c", "http://d")
+	balancer := ConsistentHash(ByHeader("X-Tenant"))
+
+	pick := func(tenant string, upstreams []*Upstream) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant", tenant)
+		return balancer.Pick(req, upstreams).URL.Host
+	}
+
+	tenants := []string{"acme", "globex", "initech", "umbrella", "hooli", "stark", "wayne", "wonka"}
+	before := map[string]string{}
+	for _, tenant := range tenants {
+		before[tenant] = pick(tenant, upstreams)
+		assert.Equal(t, before[tenant], pick(tenant, upstreams), "same key, same upstream")
+	}
+
+	// Removing an upstream only moves the keys it served.
+	remaining := upstreams[1:]
+	for _, tenant := range tenants {
+		if before[tenant] != "a" {
+			assert.Equal(t, before[tenant], pick(tenant, remaining))
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// HealthCheck configures the active health checks of a Pool.
+type HealthCheck struct {
+	Path               string        // Path requested on each upstream, healthy when answering 2xx or 3xx.
+	Interval           time.Duration // Interval between checks.
+	Timeout            time.Duration // Timeout of each check.
+	HealthyThreshold   int           // Consecutive successful checks marking an unhealthy upstream healthy.
+	UnhealthyThreshold int           // Consecutive failed checks marking a healthy upstream unhealthy.
+}
+
+// NewHealthCheck creates a new HealthCheck requesting path, with default values.
+func NewHealthCheck(path string) *HealthCheck {
+	return &HealthCheck{
+		Path:               path,
+		Interval:           10 * time.Second,
+		Timeout:            2 * time.Second,
+		HealthyThreshold:   2,
+		UnhealthyThreshold: 3,
+	}
+}
+
+// checkHealth checks the upstreams every interval until ctx is done.
+func (p *Pool) checkHealth(ctx context.Context, check *HealthCheck) {
+	client := &http.Client{Transport: p.transport, Timeout: check.Timeout}
+
+	ticker := time.NewTicker(check.Interval)
+	defer ticker.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for _, u := range p.upstreams {
+			wg.Add(1)
+			go func(u *Upstream) {
+				defer wg.Done()
+				p.recordCheck(u, probe(ctx, client, u, check.Path), check)
+			}(u)
+		}
+		wg.Wait()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe requests the health check path of the upstream, reporting if it answered 2xx or 3xx.
+func probe(ctx context.Context, client *http.Client, u *Upstream, path string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.URL.JoinPath(path).String(), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode < http.StatusBadRequest
+}
+
+// recordCheck flips the health of the upstream once enough consecutive checks contradict it.
+func (p *Pool) recordCheck(u *Upstream, ok bool, check *HealthCheck) {
+	if ok == u.healthy.Load() {
+		u.streak = 0
+		return
+	}
+
+	u.streak++
+	threshold := check.UnhealthyThreshold
+	if ok {
+		threshold = check.HealthyThreshold
+	}
+	if u.streak < threshold {
+		return
+	}
+
+	u.streak = 0
+	u.healthy.Store(ok)
+	if ok {
+		slog.Info("upstream healthy", slog.String("upstream", u.URL.Host))
+	} else {
+		slog.Warn("upstream unhealthy", slog.String("upstream", u.URL.Host))
+	}
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+)
+
+// upstreamHostKey is the attribute key for the upstream host.
+const upstreamHostKey = attribute.Key("proxy.upstream")
+
+type Metrics struct {
+	responseCounter metric.Int64Counter
+	errorCounter    metric.Int64Counter
+}
+
+// NewMetrics returns a new Metrics instance.
+func NewMetrics(meter *metric.Meter) *Metrics {
+	responseCounter, _ := (*meter).Int64Counter(
+		"http_proxy_upstream_responses_total",
+		metric.WithDescription("Total number of responses received from proxy upstreams."),
+	)
+	errorCounter, _ := (*meter).Int64Counter(
+		"http_proxy_upstream_errors_total",
+		metric.WithDescription("Total number of proxied requests failing without an upstream response."),
+	)
+
+	return &Metrics{
+		responseCounter: responseCounter,
+		errorCounter:    errorCounter,
+	}
+}
+
+// IncreaseResponseCounter increases the upstream response counter by 1.
+func (m *Metrics) IncreaseResponseCounter(ctx context.Context, upstream string, statusCode int) {
+	m.responseCounter.Add(ctx, 1, metric.WithAttributes(upstreamHostKey.String(upstream), semconv.HTTPStatusCodeKey.Int(statusCode)))
+}
+
+// IncreaseErrorCounter increases the upstream error counter by 1.
+func (m *Metrics) IncreaseErrorCounter(ctx context.Context, upstream string) {
+	m.errorCounter.Add(ctx, 1, metric.WithAttributes(upstreamHostKey.String(upstream)))
+}
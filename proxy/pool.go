@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// PoolConfig is a struct that holds configuration options for a Pool.
+type PoolConfig struct {
+	Targets       []string          // Base URLs of the upstreams, such as "http://10.0.0.1:8080".
+	Balancer      Balancer          // Strategy picking the upstream of each request.
+	HealthCheck   *HealthCheck      // Active health checks, nil disables them.
+	MaxFailures   int               // Consecutive 5xx responses or errors ejecting an upstream, 0 disables it.
+	EjectDuration time.Duration     // Duration upstreams are ejected for by passive health checks.
+	Transport     http.RoundTripper // Transport of the health checks, nil means http.DefaultTransport.
+}
+
+// NewPoolConfig creates a new PoolConfig struct with default values.
+// The Targets must be set.
+func NewPoolConfig() *PoolConfig {
+	return &PoolConfig{
+		Targets:       []string{},
+		Balancer:      RoundRobin(),
+		HealthCheck:   nil,
+		MaxFailures:   5,
+		EjectDuration: 30 * time.Second,
+		Transport:     nil,
+	}
+}
+
+// Pool is a set of upstreams serving the same routes, balanced and health checked.
+type Pool struct {
+	upstreams     []*Upstream
+	balancer      Balancer
+	maxFailures   int32
+	ejectDuration time.Duration
+	transport     http.RoundTripper
+	stop          context.CancelFunc
+}
+
+// NewPool returns a Pool of the targets, starting its active health checks if configured.
+// Close stops them.
+func NewPool(config *PoolConfig) (*Pool, error) {
+	if len(config.Targets) == 0 {
+		return nil, fmt.Errorf("no upstream targets")
+	}
+
+	p := &Pool{
+		balancer:      config.Balancer,
+		maxFailures:   int32(config.MaxFailures),
+		ejectDuration: config.EjectDuration,
+		transport:     config.Transport,
+		stop:          func() {},
+	}
+	if p.transport == nil {
+		p.transport = http.DefaultTransport
+	}
+	for _, target := range config.Targets {
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid upstream target %q", target)
+		}
+		p.upstreams = append(p.upstreams, newUpstream(u))
+	}
+
+	if config.HealthCheck != nil {
+		var ctx context.Context
+		ctx, p.stop = context.WithCancel(context.Background())
+		go p.checkHealth(ctx, config.HealthCheck)
+	}
+	return p, nil
+}
+
+// Upstreams returns the upstreams of the pool.
+func (p *Pool) Upstreams() []*Upstream {
+	return p.upstreams
+}
+
+// Close stops the active health checks.
+func (p *Pool) Close() {
+	p.stop()
+}
+
+// pick returns the upstream of the request among the available ones.
+func (p *Pool) pick(r *http.Request) (*Upstream, bool) {
+	now := time.Now()
+	available := make([]*Upstream, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		if u.Available(now) {
+			available = append(available, u)
+		}
+	}
+	if len(available) == 0 {
+		return nil, false
+	}
+	return p.balancer.Pick(r, available), true
+}
+
+// report records the outcome of a request for passive health checks, ejecting upstreams failing too often.
+func (p *Pool) report(u *Upstream, ok bool) {
+	if p.maxFailures <= 0 {
+		return
+	}
+	if ok {
+		u.failures.Store(0)
+		return
+	}
+	if u.failures.Add(1) < p.maxFailures {
+		return
+	}
+
+	u.failures.Store(0)
+	u.ejectedUntil.Store(time.Now().Add(p.ejectDuration).UnixNano())
+	slog.Warn("upstream ejected",
+		slog.String("upstream", u.URL.Host),
+		slog.Duration("duration", p.ejectDuration),
+	)
+}
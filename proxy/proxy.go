@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"reflect"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"golang.org/x/exp/slog"
+
+	"github.com/2n3g5c9/go-http/client"
+	"github.com/2n3g5c9/go-http/middlewares/common"
+	"github.com/2n3g5c9/go-http/middlewares/logging"
+	"github.com/2n3g5c9/go-http/middlewares/problem"
+)
+
+// HeaderRules are changes applied to the headers of proxied requests or responses.
+type HeaderRules = common.HeaderRules
+
+// Config is a struct that holds configuration options for the reverse proxy handler.
+type Config struct {
+	Pool            *Pool                    // Upstreams requests are forwarded to.
+	PathRewrite     func(path string) string // Function rewriting the request path, such as ReplacePrefix, nil keeps it.
+	PreserveHost    bool                     // Flag to forward the Host header instead of the upstream host.
+	RequestHeaders  HeaderRules              // Changes to the headers of forwarded requests.
+	ResponseHeaders HeaderRules              // Changes to the headers of upstream responses.
+	Transport       http.RoundTripper        // Transport of forwarded requests, nil means http.DefaultTransport.
+}
+
+// NewConfig creates a new Config struct with default values.
+// The Pool must be set.
+func NewConfig() *Config {
+	return &Config{
+		Pool:            nil,
+		PathRewrite:     nil,
+		PreserveHost:    false,
+		RequestHeaders:  HeaderRules{},
+		ResponseHeaders: HeaderRules{},
+		Transport:       nil,
+	}
+}
+
+// ReplacePrefix returns a path rewrite replacing the prefix of paths, such as "/api" with "" or "/v2".
+func ReplacePrefix(prefix, replacement string) func(string) string {
+	return func(path string) string {
+		if !strings.HasPrefix(path, prefix) {
+			return path
+		}
+		path = replacement + strings.TrimPrefix(path, prefix)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		return path
+	}
+}
+
+type upstreamKey struct{}
+
+// Handler is the reverse proxy handler function that takes a Config struct and returns the handler.
+// Requests are forwarded to an available upstream of the pool, picked by its balancer, with the X-Forwarded
+// headers set. WebSocket upgrades are passed through. The upstream call goes through a client.Transport.
+func Handler(config *Config) http.Handler {
+	var (
+		pkgName = reflect.TypeOf(struct{}{}).PkgPath()
+		meter   = otel.GetMeterProvider().Meter(pkgName)
+		metrics = NewMetrics(&meter)
+		pool    = config.Pool
+	)
+
+	transportCfg := client.NewConfig()
+	transportCfg.Base = config.Transport
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			u := pr.In.Context().Value(upstreamKey{}).(*Upstream)
+			if config.PathRewrite != nil {
+				pr.Out.URL.Path = config.PathRewrite(pr.In.URL.Path)
+				pr.Out.URL.RawPath = ""
+			}
+			pr.SetURL(u.URL)
+			pr.SetXForwarded()
+			if config.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			config.RequestHeaders.Apply(pr.Out.Header)
+		},
+		Transport: client.NewTransport(transportCfg),
+		ModifyResponse: func(resp *http.Response) error {
+			u := resp.Request.Context().Value(upstreamKey{}).(*Upstream)
+			pool.report(u, resp.StatusCode < http.StatusInternalServerError)
+			metrics.IncreaseResponseCounter(resp.Request.Context(), u.URL.Host, resp.StatusCode)
+			config.ResponseHeaders.Apply(resp.Header)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			ctx := r.Context()
+			if errors.Is(ctx.Err(), context.Canceled) {
+				// The client went away, which says nothing of the upstream health.
+				return
+			}
+
+			u := ctx.Value(upstreamKey{}).(*Upstream)
+			pool.report(u, false)
+			metrics.IncreaseErrorCounter(ctx, u.URL.Host)
+			logging.FromContext(ctx).Error("upstream request failed", slog.String("error", err.Error()))
+
+			if errors.Is(err, context.DeadlineExceeded) {
+				problem.Error(w, http.StatusGatewayTimeout, "upstream timed out")
+				return
+			}
+			problem.Error(w, http.StatusBadGateway, "upstream unavailable")
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, ok := pool.pick(r)
+		if !ok {
+			logging.FromContext(r.Context()).Error("no upstream available", slog.String("path", r.URL.Path))
+			problem.Error(w, http.StatusServiceUnavailable, "no upstream available")
+			return
+		}
+
+		u.active.Add(1)
+		defer u.active.Add(-1)
+
+		ctx := context.WithValue(r.Context(), upstreamKey{}, u)
+		ctx = logging.WithAttrs(ctx, slog.String("upstream", u.URL.Host))
+		proxy.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPool(t *testing.T, config *PoolConfig) *Pool {
+	t.Helper()
+	pool, err := NewPool(config)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream-Path", r.URL.Path)
+		w.Header().Set("X-Upstream-Tenant", r.Header.Get("X-Tenant"))
+		w.Header().Set("X-Upstream-Cookie", r.Header.Get("Cookie"))
+		w.Header().Set("X-Forwarded-Host-Seen", r.Header.Get("X-Forwarded-Host"))
+		w.Header().Set("Server", "upstream")
+	}))
+	defer upstream.Close()
+
+	poolConfig := NewPoolConfig()
+	poolConfig.Targets = []string{upstream.URL + "/base"}
+
+	config := NewConfig()
+	config.Pool = newTestPool(t, poolConfig)
+	config.PathRewrite = ReplacePrefix("/api", "")
+	config.RequestHeaders = HeaderRules{Set: map[string]string{"X-Tenant": "acme"}, Remove: []string{"Cookie"}}
+	config.ResponseHeaders = HeaderRules{Remove: []string{"Server"}}
+
+	req := httptest.NewRequest(http.MethodGet, "http://gateway.example/api/pets", nil)
+	req.Header.Set("Cookie", "session=secret")
+	rr := httptest.NewRecorder()
+
+	Handler(config).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "/base/pets", rr.Header().Get("X-Upstream-Path"))
+	assert.Equal(t, "acme", rr.Header().Get("X-Upstream-Tenant"))
+	assert.Empty(t, rr.Header().Get("X-Upstream-Cookie"))
+	assert.Equal(t, "gateway.example", rr.Header().Get("X-Forwarded-Host-Seen"))
+	assert.Empty(t, rr.Header().Get("Server"))
+}
+
+func TestHandlerPassiveHealth(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	poolConfig := NewPoolConfig()
+	poolConfig.Targets = []string{failing.URL}
+	poolConfig.MaxFailures = 2
+	poolConfig.EjectDuration = time.Minute
+
+	config := NewConfig()
+	config.Pool = newTestPool(t, poolConfig)
+	handler := Handler(config)
+
+	var got []int
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		got = append(got, rr.Code)
+	}
+	assert.Equal(t, []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusServiceUnavailable}, got)
+}
+
+func TestHandlerUnreachableUpstream(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	poolConfig := NewPoolConfig()
+	poolConfig.Targets = []string{closed.URL}
+
+	config := NewConfig()
+	config.Pool = newTestPool(t, poolConfig)
+
+	rr := httptest.NewRecorder()
+	Handler(config).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+}
+
+func TestHandlerWebSocket(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "websocket", r.Header.Get("Upgrade"))
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		_ = brw.Flush()
+		// Echo the first line back.
+		line, _ := brw.ReadString('\n')
+		_, _ = brw.WriteString(line)
+		_ = brw.Flush()
+	}))
+	defer upstream.Close()
+
+	poolConfig := NewPoolConfig()
+	poolConfig.Targets = []string{upstream.URL}
+
+	config := NewConfig()
+	config.Pool = newTestPool(t, poolConfig)
+	gateway := httptest.NewServer(Handler(config))
+	defer gateway.Close()
+
+	conn, err := net.Dial("tcp", gateway.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: gateway\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	_, err = io.WriteString(conn, "ping\n")
+	require.NoError(t, err)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "ping\n", line)
+}
+
+func TestPoolActiveHealth(t *testing.T) {
+	var unhealthy atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/healthz", r.URL.Path)
+		if unhealthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer upstream.Close()
+
+	check := NewHealthCheck("/healthz")
+	check.Interval = 5 * time.Millisecond
+	check.UnhealthyThreshold = 2
+
+	poolConfig := NewPoolConfig()
+	poolConfig.Targets = []string{upstream.URL}
+	poolConfig.HealthCheck = check
+	pool := newTestPool(t, poolConfig)
+
+	u := pool.Upstreams()[0]
+	assert.True(t, u.Available(time.Now()))
+
+	unhealthy.Store(true)
+	assert.Eventually(t, func() bool { return !u.Available(time.Now()) }, time.Second, 5*time.Millisecond)
+}
+
+func TestReplacePrefix(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefix      string
+		replacement string
+		path        string
+		want        string
+	}{
+		{"Stripped prefix", "/api", "", "/api/pets", "/pets"},
+		{"Stripped whole path", "/api", "", "/api", "/"},
+		{"Replaced prefix", "/api", "/v2", "/api/pets", "/v2/pets"},
+		{"Other prefix", "/api", "", "/static/app.js", "/static/app.js"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ReplacePrefix(tt.prefix, tt.replacement)(tt.path))
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package proxy
+
+import (
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// Upstream is a server of a Pool.
+type Upstream struct {
+	URL *url.URL // Base URL requests are forwarded to.
+
+	active       atomic.Int64 // Requests in flight, including open WebSocket connections.
+	failures     atomic.Int32 // Consecutive failed requests, for passive health checks.
+	ejectedUntil atomic.Int64 // Unix time in nanoseconds until which passive health checks eject the upstream.
+	healthy      atomic.Bool  // Result of the active health checks.
+	streak       int          // Consecutive active health check results contradicting healthy, owned by the checker.
+}
+
+// newUpstream returns a healthy Upstream.
+func newUpstream(u *url.URL) *Upstream {
+	upstream := &Upstream{URL: u}
+	upstream.healthy.Store(true)
+	return upstream
+}
+
+// Active returns the number of requests in flight.
+func (u *Upstream) Active() int64 {
+	return u.active.Load()
+}
+
+// Available reports whether the upstream passes its health checks and isn't ejected.
+func (u *Upstream) Available(now time.Time) bool {
+	return u.healthy.Load() && now.UnixNano() >= u.ejectedUntil.Load()
+}
@@ -1,24 +1,49 @@
 package go_http
 
 import (
+	"io"
 	"net/http"
 
+	"github.com/2n3g5c9/go-http/health"
+	"github.com/2n3g5c9/go-http/middlewares/auth/apikey"
+	"github.com/2n3g5c9/go-http/middlewares/auth/google"
+	"github.com/2n3g5c9/go-http/middlewares/auth/jwt"
+	"github.com/2n3g5c9/go-http/middlewares/authz"
+	"github.com/2n3g5c9/go-http/middlewares/bodylimit"
+	"github.com/2n3g5c9/go-http/middlewares/cache"
+	"github.com/2n3g5c9/go-http/middlewares/compress"
+	"github.com/2n3g5c9/go-http/middlewares/concurrency"
 	"github.com/2n3g5c9/go-http/middlewares/cors"
+	"github.com/2n3g5c9/go-http/middlewares/csrf"
+	"github.com/2n3g5c9/go-http/middlewares/decompress"
+	"github.com/2n3g5c9/go-http/middlewares/forwarded"
+	"github.com/2n3g5c9/go-http/middlewares/idempotency"
+	"github.com/2n3g5c9/go-http/middlewares/ipfilter"
 	"github.com/2n3g5c9/go-http/middlewares/logging"
+	"github.com/2n3g5c9/go-http/middlewares/mirror"
+	"github.com/2n3g5c9/go-http/middlewares/negotiation"
+	"github.com/2n3g5c9/go-http/middlewares/openapi"
+	"github.com/2n3g5c9/go-http/middlewares/ratelimit"
+	"github.com/2n3g5c9/go-http/middlewares/requestid"
+	"github.com/2n3g5c9/go-http/middlewares/secure"
+	"github.com/2n3g5c9/go-http/middlewares/session"
 	"github.com/2n3g5c9/go-http/middlewares/telemetry"
+	"github.com/2n3g5c9/go-http/proxy"
 )
 
 // Router is a custom HTTP router that supports middlewares.
 type Router struct {
 	*http.ServeMux
+	probes      *http.ServeMux
 	middlewares []Middleware
+	authzConfig *authz.Config
 }
 
 // NewRouter creates a new Router with the specified MiddlewareOptions.
 // It sets up middlewares for CORS, logging, and tracing based on the provided options.
 func NewRouter(opts ...MiddlewareOption) *Router {
 	var (
-		r       = Router{ServeMux: http.NewServeMux(), middlewares: []Middleware{}}
+		r       = Router{ServeMux: http.NewServeMux(), probes: http.NewServeMux(), middlewares: []Middleware{}}
 		options = &middlewareOptions{}
 	)
 
@@ -26,6 +51,154 @@ func NewRouter(opts ...MiddlewareOption) *Router {
 		opt(options)
 	}
 
+	// Configure and add cache middleware if cache options are provided.
+	// It runs closest to the handlers so that cached responses still go through authentication and authorization.
+	if options.Cache != nil {
+		cacheCfg := cache.NewConfig()
+		if options.Cache.MaxBytes > 0 {
+			cacheCfg.MaxBytes = options.Cache.MaxBytes
+		}
+		if options.Cache.ExcludedPrefixes != nil {
+			cacheCfg.ExcludedPrefixes = options.Cache.ExcludedPrefixes
+		}
+		cacheCfg.Store = options.Cache.Store
+		if options.APIKey != nil && options.APIKey.Header != "" {
+			cacheCfg.CredentialHeaders = append(cacheCfg.CredentialHeaders, options.APIKey.Header)
+		}
+		r.middlewares = append(r.middlewares, cache.Middleware(cacheCfg))
+	}
+
+	// Configure and add mirroring middleware if mirror options are provided.
+	// It runs outside the cache so that cached responses are mirrored too, and inside authentication so that
+	// rejected requests aren't.
+	if options.Mirror != nil {
+		mirrorCfg := mirror.NewConfig()
+		mirrorCfg.Target = options.Mirror.Target
+		mirrorCfg.DefaultRatio = options.Mirror.DefaultRatio
+		if options.Mirror.RouteRatios != nil {
+			mirrorCfg.RouteRatios = options.Mirror.RouteRatios
+		}
+		mirrorCfg.Diff = options.Mirror.Diff
+		if options.APIKey != nil && options.APIKey.Header != "" {
+			mirrorCfg.RequestHeaders.Remove = append(mirrorCfg.RequestHeaders.Remove, options.APIKey.Header)
+		}
+		r.middlewares = append(r.middlewares, mirror.Middleware(mirrorCfg))
+	}
+
+	// Configure and add content negotiation middleware if content negotiation options are provided.
+	if options.Negotiation != nil {
+		negotiationCfg := negotiation.NewConfig()
+		if options.Negotiation.Registry != nil {
+			negotiationCfg.Registry = options.Negotiation.Registry
+		}
+		if options.Negotiation.ExcludedPrefixes != nil {
+			negotiationCfg.ExcludedPrefixes = options.Negotiation.ExcludedPrefixes
+		}
+		r.middlewares = append(r.middlewares, negotiation.Middleware(negotiationCfg))
+	}
+
+	// Configure and add idempotency middleware if idempotency options are provided.
+	// It runs inside the authentication middlewares to scope keys to the caller.
+	if options.Idempotency != nil {
+		idempotencyCfg := idempotency.NewConfig()
+		idempotencyCfg.Store = options.Idempotency.Store
+		idempotencyCfg.Required = options.Idempotency.Required
+		idempotencyCfg.WaitTimeout = options.Idempotency.WaitTimeout
+		r.middlewares = append(r.middlewares, idempotency.Middleware(idempotencyCfg))
+	}
+
+	// Configure and add OpenAPI validation middleware if OpenAPI options are provided.
+	// It runs inside the authentication middlewares so unauthenticated requests aren't validated.
+	if options.OpenAPI != nil {
+		openapiCfg := openapi.NewConfig()
+		openapiCfg.Document = options.OpenAPI.Document
+		openapiCfg.ValidateResponses = options.OpenAPI.ValidateResponses
+		r.middlewares = append(r.middlewares, openapi.Middleware(openapiCfg))
+	}
+
+	// Configure and add authorization middleware if authorization options are provided.
+	// It runs inside the authentication middlewares, which provide the identity.
+	if options.Authz != nil {
+		r.authzConfig = authz.NewConfig()
+		if options.Authz.RoutePolicies != nil {
+			r.authzConfig.RoutePolicies = options.Authz.RoutePolicies
+		}
+		r.authzConfig.DefaultPolicy = options.Authz.DefaultPolicy
+		r.middlewares = append(r.middlewares, authz.Middleware(r.authzConfig))
+	}
+
+	// Configure and add CSRF middleware if CSRF options are provided.
+	// It runs inside the authentication middlewares to exempt requests authenticated with an API key.
+	if options.CSRF != nil {
+		csrfCfg := csrf.NewConfig()
+		if options.CORS != nil {
+			csrfCfg.TrustedOrigins = options.CORS.AllowedOrigins
+		}
+		if options.CSRF.SessionID != nil {
+			csrfCfg.Mode = csrf.Synchronizer
+			csrfCfg.Secret = options.CSRF.Secret
+			csrfCfg.SessionID = options.CSRF.SessionID
+		}
+		if options.CSRF.ExcludedPrefixes != nil {
+			csrfCfg.ExcludedPrefixes = options.CSRF.ExcludedPrefixes
+		}
+		r.middlewares = append(r.middlewares, csrf.Middleware(csrfCfg))
+	}
+
+	// Configure and add API key authentication middleware if API key options are provided.
+	var redactedQueryParams []string
+	if options.APIKey != nil {
+		apiKeyCfg := apikey.NewConfig()
+		apiKeyCfg.Store = options.APIKey.Store
+		if options.APIKey.Header != "" {
+			apiKeyCfg.Header = options.APIKey.Header
+		}
+		apiKeyCfg.QueryParam = options.APIKey.QueryParam
+		if options.APIKey.ExcludedPrefixes != nil {
+			apiKeyCfg.ExcludedPrefixes = options.APIKey.ExcludedPrefixes
+		}
+		if options.APIKey.QueryParam != "" {
+			redactedQueryParams = append(redactedQueryParams, options.APIKey.QueryParam)
+		}
+		r.middlewares = append(r.middlewares, apikey.Middleware(apiKeyCfg))
+	}
+
+	// Configure and add JWT authentication middleware if JWT options are provided.
+	// It runs inside the CORS middleware so that preflight requests don't need a token.
+	if options.JWT != nil {
+		jwtCfg := jwt.NewConfig()
+		jwtCfg.KeySource = options.JWT.KeySource
+		if options.JWT.Issuer != "" {
+			jwtCfg.Issuers = []string{options.JWT.Issuer}
+		}
+		if options.JWT.Audiences != nil {
+			jwtCfg.Audiences = options.JWT.Audiences
+		}
+		if options.JWT.ExcludedPrefixes != nil {
+			jwtCfg.ExcludedPrefixes = options.JWT.ExcludedPrefixes
+		}
+		r.middlewares = append(r.middlewares, jwt.Middleware(jwtCfg))
+	}
+
+	// Configure and add Identity-Aware Proxy middleware if IAP options are provided.
+	if options.IAP != nil {
+		r.middlewares = append(r.middlewares, google.IAPMiddleware(googleConfig(google.NewIAPConfig(), options.IAP)))
+	}
+
+	// Configure and add Google ID token middleware if ID token options are provided.
+	if options.IDToken != nil {
+		r.middlewares = append(r.middlewares, google.IDTokenMiddleware(googleConfig(google.NewIDTokenConfig(), options.IDToken)))
+	}
+
+	// Configure and add session middleware if session options are provided.
+	// It runs outside the CSRF and authentication middlewares so that they can rely on the session.
+	if options.Session != nil {
+		sessionCfg := session.NewConfig()
+		sessionCfg.Keys = options.Session.Keys
+		sessionCfg.Store = options.Session.Store
+		r.middlewares = append(r.middlewares, session.Middleware(sessionCfg))
+	}
+
 	// Configure and add CORS middleware if CORS options are provided.
 	if options.CORS != nil {
 		corsCfg := cors.NewConfig()
@@ -34,11 +207,99 @@ func NewRouter(opts ...MiddlewareOption) *Router {
 		r.middlewares = append(r.middlewares, cors.Middleware(corsCfg))
 	}
 
+	// Configure and add compression middleware if compression options are provided.
+	if options.Compress != nil {
+		compressCfg := compress.NewConfig()
+		compressCfg.MinSize = options.Compress.MinSize
+		if options.Compress.ContentTypes != nil {
+			compressCfg.ContentTypes = options.Compress.ContentTypes
+		}
+		r.middlewares = append(r.middlewares, compress.Middleware(compressCfg))
+	}
+
+	// Configure and add request decompression middleware if decompression options are provided.
+	// It runs inside the body limit middleware, which then bounds the compressed size.
+	if options.Decompress != nil {
+		decompressCfg := decompress.NewConfig()
+		decompressCfg.MaxDecompressed = options.Decompress.MaxDecompressed
+		r.middlewares = append(r.middlewares, decompress.Middleware(decompressCfg))
+	}
+
+	// Configure and add body limit middleware if body limit options are provided.
+	if options.BodyLimit != nil {
+		bodyLimitCfg := bodylimit.NewConfig()
+		bodyLimitCfg.DefaultLimit = options.BodyLimit.DefaultLimit
+		if options.BodyLimit.RouteLimits != nil {
+			bodyLimitCfg.RouteLimits = options.BodyLimit.RouteLimits
+		}
+		bodyLimitCfg.Budget = options.BodyLimit.Budget
+		bodyLimitCfg.QueueTimeout = options.BodyLimit.QueueTimeout
+		r.middlewares = append(r.middlewares, bodylimit.Middleware(bodyLimitCfg))
+	}
+
+	// Configure and add rate limit middleware if rate limit options are provided.
+	if options.RateLimit != nil {
+		rateLimitCfg := ratelimit.NewConfig()
+		rateLimitCfg.DefaultLimit = options.RateLimit.DefaultLimit
+		if options.RateLimit.RouteLimits != nil {
+			rateLimitCfg.RouteLimits = options.RateLimit.RouteLimits
+		}
+		if options.RateLimit.KeyFunc != nil {
+			rateLimitCfg.KeyFunc = options.RateLimit.KeyFunc
+		}
+		rateLimitCfg.Store = options.RateLimit.Store
+		rateLimitCfg.FailurePolicy = options.RateLimit.FailurePolicy
+		r.middlewares = append(r.middlewares, ratelimit.Middleware(rateLimitCfg))
+	}
+
+	// Configure and add concurrency limit middleware if concurrency options are provided.
+	if options.Concurrency != nil {
+		concurrencyCfg := concurrency.NewConfig()
+		if options.Concurrency.Algorithm != nil {
+			concurrencyCfg.Algorithm = options.Concurrency.Algorithm
+		}
+		if options.Concurrency.CriticalPrefixes != nil {
+			concurrencyCfg.CriticalPrefixes = options.Concurrency.CriticalPrefixes
+		}
+		concurrencyCfg.MaxQueue = options.Concurrency.MaxQueue
+		if options.Concurrency.QueueTimeout > 0 {
+			concurrencyCfg.QueueTimeout = options.Concurrency.QueueTimeout
+		}
+		r.middlewares = append(r.middlewares, concurrency.Middleware(concurrencyCfg))
+	}
+
+	// Configure and add IP filter middleware if IP filter options are provided.
+	if options.IPFilter != nil {
+		ipFilterCfg := ipfilter.NewConfig()
+		ipFilterCfg.DefaultRule = options.IPFilter.DefaultRule
+		if options.IPFilter.RouteRules != nil {
+			ipFilterCfg.RouteRules = options.IPFilter.RouteRules
+		}
+		r.middlewares = append(r.middlewares, ipfilter.Middleware(ipFilterCfg))
+	}
+
+	// Configure and add security headers middleware if security headers options are provided.
+	// It runs outside the other middlewares so that their rejections get the headers too.
+	if options.Secure != nil {
+		secureCfg := secure.NewConfig()
+		if options.Secure.ContentSecurityPolicy != "" {
+			secureCfg.ContentSecurityPolicy = options.Secure.ContentSecurityPolicy
+		}
+		if options.Secure.RoutePolicies != nil {
+			secureCfg.RoutePolicies = options.Secure.RoutePolicies
+		}
+		secureCfg.ReportOnly = options.Secure.ReportOnly
+		r.middlewares = append(r.middlewares, secure.Middleware(secureCfg))
+	}
+
 	// Configure and add logging middleware if logging options are provided.
 	if options.Logging != nil {
 		r.middlewares = append(r.middlewares,
 			func(next http.Handler) http.Handler {
-				return logging.Middleware(next, logging.WithExcludedPrefixes(options.Logging.ExcludedPrefixes))
+				return logging.Middleware(next,
+					logging.WithExcludedPrefixes(options.Logging.ExcludedPrefixes),
+					logging.WithRedactedQueryParams(redactedQueryParams),
+				)
 			})
 	}
 
@@ -46,16 +307,83 @@ func NewRouter(opts ...MiddlewareOption) *Router {
 	if options.Telemetry != nil {
 		r.middlewares = append(r.middlewares,
 			func(next http.Handler) http.Handler {
-				return telemetry.Middleware(next, telemetry.WithExcludedPrefixes(options.Telemetry.ExcludedPrefixes))
+				return telemetry.Middleware(next,
+					telemetry.WithExcludedPrefixes(options.Telemetry.ExcludedPrefixes),
+					telemetry.WithRedactedQueryParams(redactedQueryParams),
+				)
 			})
 	}
 
+	// Configure and add request ID middleware if request ID options are provided.
+	// It runs outside the logging middleware so that request logs carry the ID.
+	if options.RequestID != nil {
+		requestIDCfg := requestid.NewConfig()
+		if options.RequestID.Header != "" {
+			requestIDCfg.Header = options.RequestID.Header
+		}
+		r.middlewares = append(r.middlewares, requestid.Middleware(requestIDCfg))
+	}
+
+	// Configure and add forwarded headers middleware if trusted proxies are provided.
+	// It runs first so that every other middleware sees the resolved client address, scheme and host.
+	if options.Forwarded != nil {
+		forwardedCfg := forwarded.NewConfig()
+		if options.Forwarded.TrustedProxies != nil {
+			forwardedCfg.TrustedProxies = options.Forwarded.TrustedProxies
+		}
+		if options.Forwarded.Headers != nil {
+			forwardedCfg.Headers = options.Forwarded.Headers
+		}
+		r.middlewares = append(r.middlewares, forwarded.Middleware(forwardedCfg))
+	}
+
 	return &r
 }
 
+// googleConfig applies the Google identity options to the default config.
+func googleConfig(cfg *google.Config, option *GoogleIdentityOption) *google.Config {
+	if option.KeySource != nil {
+		cfg.KeySource = option.KeySource
+	}
+	cfg.Audiences = option.Audiences
+	if option.ExcludedPrefixes != nil {
+		cfg.ExcludedPrefixes = option.ExcludedPrefixes
+	}
+	return cfg
+}
+
+// WriteAuthorizationPolicies writes the route-to-policy map enforced by the authorization middleware as a table.
+func (r *Router) WriteAuthorizationPolicies(w io.Writer) error {
+	if r.authzConfig == nil {
+		_, err := io.WriteString(w, "authorization disabled\n")
+		return err
+	}
+	return r.authzConfig.WriteRoutes(w)
+}
+
+// HandleProxy registers a reverse proxy route for the pattern, forwarding requests to the upstreams of the
+// config pool. Proxied requests go through the configured middlewares like any other route.
+func (r *Router) HandleProxy(pattern string, config *proxy.Config) {
+	r.Handle(pattern, proxy.Handler(config))
+}
+
+// HandleReadiness registers a readiness endpoint for the pattern, answering 503 while a checker fails, such as
+// client.Breakers with an open circuit breaker. Probes are served outside the middlewares, so that authentication,
+// rate limiting or IP filtering never fail them.
+func (r *Router) HandleReadiness(pattern string, checkers map[string]health.Checker) {
+	cfg := health.NewConfig()
+	cfg.Checkers = checkers
+	r.probes.Handle(pattern, health.Handler(cfg))
+}
+
 // HandlerFunc method returns a http.HandlerFunc that wraps the Router with the configured middlewares.
 func (r *Router) HandlerFunc() *http.HandlerFunc {
 	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if probe, pattern := r.probes.Handler(req); pattern != "" {
+			probe.ServeHTTP(w, req)
+			return
+		}
+
 		wrappedHandler := http.Handler(r)
 		for _, middleware := range r.middlewares {
 			wrappedHandler = middleware(wrappedHandler)